@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Novometrix/util/middleware"
+	"github.com/Novometrix/util/util"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidResponse = errors.New("response is not a valid BaseResponse")
+)
+
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// ResponseError is returned for responses with a non-2xx status code.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	RequestID  string
+	// Body is the payload of the BaseResponse, or the raw response body if it is not a BaseResponse.
+	Body []byte
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("request %s failed with status %d: %s", e.RequestID, e.StatusCode, e.Body)
+}
+
+type Client struct {
+	httpClient *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func NewClient(options ...func(*Client)) *Client {
+	c := &Client{
+		httpClient: &http.Client{
+			Transport: middleware.RequestIDTransport{},
+			Timeout:   30 * time.Second,
+		},
+		maxRetries: 2,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 2 * time.Second,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithHTTPClient sets the underlying http.Client.
+// Its transport should be wrapped in a middleware.RequestIDTransport to keep propagating request IDs.
+func WithHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithMaxRetries sets how many times idempotent requests are retried after a network error, a 429 or a 5xx response.
+func WithMaxRetries(n int) func(*Client) {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
+}
+
+// WithBackoff sets the bounds of the exponential backoff between retries.
+func WithBackoff(min, max time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// Do sends req and decodes the BaseResponse it returns.
+// Non-2xx responses are returned as a *ResponseError.
+// The request ID of the request context, see middleware.ContextWithRequestID, is sent in the X-Request-ID header.
+func Do[T any](c *Client, req *http.Request) (middleware.BaseResponse[T], error) {
+	var resp middleware.BaseResponse[T]
+
+	middleware.SetRequestIDHeader(req)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	res, err := c.send(req)
+	if err != nil {
+		return resp, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return resp, newResponseError(res, body)
+	}
+
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return resp, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	return resp, nil
+}
+
+// Get sends a GET request to url and decodes the BaseResponse it returns.
+func Get[T any](ctx context.Context, c *Client, url string) (middleware.BaseResponse[T], error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return middleware.BaseResponse[T]{}, err
+	}
+
+	return Do[T](c, req)
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	retries := 0
+	if util.SliceContains(idempotentMethods, req.Method) && (req.Body == nil || req.GetBody != nil) {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		res, err := c.httpClient.Do(req)
+		if attempt >= retries || !shouldRetry(res, err) {
+			return res, err
+		}
+
+		wait := c.backoff(attempt)
+		if res != nil {
+			if d, ok := retryAfter(res); ok {
+				// Rather than waiting longer than the configured backoff, the response is returned as is.
+				if d > c.maxBackoff {
+					return res, nil
+				}
+				wait = d
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the delay before the retry following attempt, using exponential backoff with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests ||
+		(res.StatusCode >= 500 && res.StatusCode != http.StatusNotImplemented)
+}
+
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	s, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0, false
+	}
+
+	return time.Duration(s) * time.Second, true
+}
+
+func newResponseError(res *http.Response, body []byte) *ResponseError {
+	e := &ResponseError{
+		StatusCode: res.StatusCode,
+		Status:     http.StatusText(res.StatusCode),
+		RequestID:  res.Header.Get(middleware.RequestIDHeader),
+		Body:       body,
+	}
+
+	var envelope middleware.BaseResponse[json.RawMessage]
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.StatusCode != 0 {
+		e.Status = envelope.Status
+		e.Body = envelope.Payload
+		if envelope.RequestID != "" {
+			e.RequestID = envelope.RequestID
+		}
+	}
+
+	return e
+}
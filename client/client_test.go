@@ -0,0 +1,176 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Novometrix/util/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testID = "043c8c94-dbbb-4cad-b6df-663df0fcdc31"
+)
+
+type payload struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestDo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		method  string
+		body    []byte
+		handler func(calls int32) gin.HandlerFunc
+		calls   int32
+		verify  func(*testing.T, middleware.BaseResponse[payload], error)
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					middleware.OK(c, payload{ID: 1 << 60, Name: c.GetHeader(middleware.RequestIDHeader)})
+				}
+			},
+			calls: 1,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, payload{ID: 1 << 60, Name: testID}, resp.Payload)
+				assert.Equal(t, testID, resp.RequestID)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name:   "error_response",
+			method: http.MethodGet,
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					middleware.Fail(c, http.StatusNotFound, gin.H{"error": "not found"})
+				}
+			},
+			calls: 1,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				var respErr *ResponseError
+				assert.True(t, errors.As(err, &respErr))
+				assert.Equal(t, http.StatusNotFound, respErr.StatusCode)
+				assert.Equal(t, testID, respErr.RequestID)
+				assert.JSONEq(t, `{"error":"not found"}`, string(respErr.Body))
+			},
+		},
+		{
+			name:   "success_retry_idempotent",
+			method: http.MethodPut,
+			body:   []byte(`{"name":"hi"}`),
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					if calls < 3 {
+						middleware.Fail(c, http.StatusServiceUnavailable, gin.H{})
+						return
+					}
+					b, _ := io.ReadAll(c.Request.Body)
+					middleware.OK(c, payload{Name: string(b)})
+				}
+			},
+			calls: 3,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, `{"name":"hi"}`, resp.Payload.Name)
+			},
+		},
+		{
+			name:   "error_no_retry_non_idempotent",
+			method: http.MethodPost,
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					middleware.Fail(c, http.StatusServiceUnavailable, gin.H{})
+				}
+			},
+			calls: 1,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				var respErr *ResponseError
+				assert.True(t, errors.As(err, &respErr))
+				assert.Equal(t, http.StatusServiceUnavailable, respErr.StatusCode)
+			},
+		},
+		{
+			name:   "error_retries_exhausted",
+			method: http.MethodGet,
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					middleware.Fail(c, http.StatusBadGateway, gin.H{})
+				}
+			},
+			calls: 3,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				var respErr *ResponseError
+				assert.True(t, errors.As(err, &respErr))
+				assert.Equal(t, http.StatusBadGateway, respErr.StatusCode)
+			},
+		},
+		{
+			name:   "error_invalid_response",
+			method: http.MethodGet,
+			handler: func(calls int32) gin.HandlerFunc {
+				return func(c *gin.Context) {
+					c.String(http.StatusOK, "not json")
+				}
+			},
+			calls: 1,
+			verify: func(t *testing.T, resp middleware.BaseResponse[payload], err error) {
+				assert.ErrorIs(t, err, ErrInvalidResponse)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+
+			e := gin.New()
+			e.Use(middleware.RequestIDMiddleware())
+			e.Any("/", func(c *gin.Context) {
+				tt.handler(atomic.AddInt32(&calls, 1))(c)
+			})
+
+			srv := httptest.NewServer(e)
+			defer srv.Close()
+
+			c := NewClient(WithMaxRetries(2), WithBackoff(time.Millisecond, 5*time.Millisecond))
+
+			ctx := middleware.ContextWithRequestID(context.Background(), testID)
+			req, _ := http.NewRequestWithContext(ctx, tt.method, srv.URL, bytes.NewReader(tt.body))
+
+			resp, err := Do[payload](c, req)
+
+			tt.verify(t, resp, err)
+			assert.Equal(t, tt.calls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestGet_ContextCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := NewClient(WithBackoff(time.Millisecond, 5*time.Second))
+	_, err := Get[payload](ctx, c, srv.URL)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+type accessLog struct {
+	logger        log.FieldLogger
+	sampleRate    float64
+	excludedPaths []string
+	level         func(status int) log.Level
+	subjectKey    string
+}
+
+// WithAccessLogger sets the logger the entries are written to. Defaults to the logrus standard logger.
+func WithAccessLogger(l log.FieldLogger) func(*accessLog) {
+	return func(al *accessLog) {
+		al.logger = l
+	}
+}
+
+// WithSampleRate sets the fraction, between 0 and 1, of the requests logged below the warning level.
+// Warnings and errors are always logged. Defaults to 1.
+func WithSampleRate(rate float64) func(*accessLog) {
+	return func(al *accessLog) {
+		al.sampleRate = rate
+	}
+}
+
+// WithExcludedPaths disables logging for the given route templates or request paths, e.g. health checks.
+func WithExcludedPaths(paths ...string) func(*accessLog) {
+	return func(al *accessLog) {
+		al.excludedPaths = paths
+	}
+}
+
+// WithLevelFunc sets the function choosing the level of an entry from the response status.
+// Defaults to error for 5xx, warning for 4xx and info otherwise.
+func WithLevelFunc(f func(status int) log.Level) func(*accessLog) {
+	return func(al *accessLog) {
+		al.level = f
+	}
+}
+
+// WithSubjectContextKey sets the gin context key of the authenticated claims, see authentication.WithContextKey.
+// Defaults to "user".
+func WithSubjectContextKey(k string) func(*accessLog) {
+	return func(al *accessLog) {
+		al.subjectKey = k
+	}
+}
+
+// AccessLogMiddleware writes one structured log entry per request, once it has been handled.
+// The middleware should be registered first, so that the latency and size cover the whole response, and before
+// RecoveryMiddleware, so that the 500 response of a recovered panic is logged. Registered after it, panicking requests
+// are still logged, as 500s with a "panic" field, before the panic reaches RecoveryMiddleware.
+func AccessLogMiddleware(options ...func(*accessLog)) gin.HandlerFunc {
+	al := &accessLog{
+		logger:     log.StandardLogger(),
+		sampleRate: 1,
+		level:      defaultAccessLogLevel,
+		subjectKey: "user",
+	}
+
+	for _, opt := range options {
+		opt(al)
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		handled := false
+
+		// The entry is written from a defer, without recovering, so that panics passing through are logged as well.
+		defer func() {
+			al.log(c, start, !handled)
+		}()
+
+		c.Next()
+		handled = true
+	}
+}
+
+func (al *accessLog) log(c *gin.Context, start time.Time, panicked bool) {
+	route := c.FullPath()
+	if util.SliceContains(al.excludedPaths, route) || util.SliceContains(al.excludedPaths, c.Request.URL.Path) {
+		return
+	}
+
+	status := c.Writer.Status()
+	if panicked {
+		status = http.StatusInternalServerError
+	}
+	level := al.level(status)
+	if level > log.WarnLevel && al.sampleRate < 1 && rand.Float64() >= al.sampleRate {
+		return
+	}
+
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+
+	headers := getRequestHeaders(c)
+	clientIP := headers.RemoteAddress
+	if clientIP == "" {
+		clientIP = c.ClientIP()
+	}
+	requestID, ok := RequestIDFrom(c)
+	if !ok {
+		requestID = headers.RequestID
+	}
+
+	fields := log.Fields{
+		"method":     c.Request.Method,
+		"route":      route,
+		"path":       c.Request.URL.Path,
+		"status":     status,
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"bytes":      size,
+		"client_ip":  clientIP,
+		"request_id": requestID,
+		"user_agent": c.Request.UserAgent(),
+	}
+	if subject := subjectFrom(c, al.subjectKey); subject != "" {
+		fields["subject"] = subject
+	}
+	if len(c.Errors) > 0 {
+		fields["errors"] = c.Errors.String()
+	}
+	if panicked {
+		fields["panic"] = true
+	}
+
+	al.logger.WithFields(fields).Log(level, "access")
+}
+
+func defaultAccessLogLevel(status int) log.Level {
+	switch {
+	case status >= 500:
+		return log.ErrorLevel
+	case status >= 400:
+		return log.WarnLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+// subjectFrom returns the subject of the claims stored under key, e.g. a jwt.MapClaims.
+func subjectFrom(c *gin.Context, key string) string {
+	claims, exists := c.Get(key)
+	if !exists {
+		return ""
+	}
+
+	switch claims := claims.(type) {
+	case interface{ GetSubject() (string, error) }:
+		sub, _ := claims.GetSubject()
+		return sub
+	case map[string]any:
+		sub, _ := claims["sub"].(string)
+		return sub
+	default:
+		return ""
+	}
+}
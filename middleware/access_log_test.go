@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		options []func(*accessLog)
+		path    string
+		status  int
+		claims  any
+		verify  func(*testing.T, *test.Hook)
+	}{
+		{
+			name:   "success_info",
+			path:   "/items/1",
+			status: http.StatusOK,
+			claims: jwt.MapClaims{"sub": "subject"},
+			verify: func(t *testing.T, hook *test.Hook) {
+				a := assert.New(t)
+				a.Len(hook.Entries, 1)
+
+				entry := hook.LastEntry()
+				a.Equal(log.InfoLevel, entry.Level)
+				a.Equal(http.MethodGet, entry.Data["method"])
+				a.Equal("/items/:id", entry.Data["route"])
+				a.Equal("/items/1", entry.Data["path"])
+				a.Equal(http.StatusOK, entry.Data["status"])
+				a.Equal(2, entry.Data["bytes"])
+				a.Equal("203.0.113.7", entry.Data["client_ip"])
+				a.Equal(TestID, entry.Data["request_id"])
+				a.Equal("subject", entry.Data["subject"])
+				a.Contains(entry.Data, "latency_ms")
+			},
+		},
+		{
+			name:   "success_level_by_status",
+			path:   "/items/1",
+			status: http.StatusInternalServerError,
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Equal(t, log.ErrorLevel, hook.LastEntry().Level)
+				assert.NotContains(t, hook.LastEntry().Data, "subject")
+			},
+		},
+		{
+			name:    "success_custom_level",
+			options: []func(*accessLog){WithLevelFunc(func(status int) log.Level { return log.DebugLevel })},
+			path:    "/items/1",
+			status:  http.StatusOK,
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Equal(t, log.DebugLevel, hook.LastEntry().Level)
+			},
+		},
+		{
+			name:    "success_excluded_path",
+			options: []func(*accessLog){WithExcludedPaths("/items/:id")},
+			path:    "/items/1",
+			status:  http.StatusOK,
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Empty(t, hook.Entries)
+			},
+		},
+		{
+			name:    "success_sampled_out",
+			options: []func(*accessLog){WithSampleRate(0)},
+			path:    "/items/1",
+			status:  http.StatusOK,
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Empty(t, hook.Entries)
+			},
+		},
+		{
+			name:    "success_sampling_keeps_warnings",
+			options: []func(*accessLog){WithSampleRate(0)},
+			path:    "/items/1",
+			status:  http.StatusNotFound,
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Len(t, hook.Entries, 1)
+			},
+		},
+		{
+			name:    "success_subject_context_key",
+			options: []func(*accessLog){WithSubjectContextKey("claims")},
+			path:    "/items/1",
+			status:  http.StatusOK,
+			claims:  map[string]any{"sub": "subject"},
+			verify: func(t *testing.T, hook *test.Hook) {
+				assert.Equal(t, "subject", hook.LastEntry().Data["subject"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			logger.SetLevel(log.DebugLevel)
+
+			options := append([]func(*accessLog){WithAccessLogger(logger)}, tt.options...)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(AccessLogMiddleware(options...))
+			e.GET("/items/:id", func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set("user", tt.claims)
+					c.Set("claims", tt.claims)
+				}
+				c.String(tt.status, "ok")
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(RequestIDHeader, TestID)
+			req.Header.Set("X-Original-Remote-Addr", "203.0.113.7")
+			e.ServeHTTP(w, req)
+
+			tt.verify(t, hook)
+		})
+	}
+}
+
+func TestAccessLogMiddleware_Panic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		recoveryFirst bool
+	}{
+		{name: "success_access_log_first"},
+		{name: "success_recovery_first", recoveryFirst: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			logger, hook := test.NewNullLogger()
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			if tt.recoveryFirst {
+				e.Use(RecoveryMiddleware(), AccessLogMiddleware(WithAccessLogger(logger)))
+			} else {
+				e.Use(AccessLogMiddleware(WithAccessLogger(logger)), RecoveryMiddleware())
+			}
+			e.GET("/", func(c *gin.Context) {
+				panic("boom")
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			e.ServeHTTP(w, req)
+
+			a.Equal(http.StatusInternalServerError, w.Code)
+			if a.Len(hook.AllEntries(), 1) {
+				entry := hook.LastEntry()
+				a.Equal(log.ErrorLevel, entry.Level)
+				a.Equal(http.StatusInternalServerError, entry.Data["status"])
+				_, panicked := entry.Data["panic"]
+				a.Equal(tt.recoveryFirst, panicked)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package authentication
+
+// TestingT is the subset of *testing.T used by AssertDecision.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertDecision asserts that the engine decides the named policy for attrs as expected, and returns whether it did.
+//
+//	AssertDecision(t, engine, "edit-document", Attributes{
+//		"subject":  map[string]any{"sub": "alice"},
+//		"resource": map[string]any{"owner": "alice"},
+//	}, true)
+func AssertDecision(t TestingT, e PolicyEngine, policy string, attrs Attributes, allowed bool) bool {
+	t.Helper()
+
+	d := e.Evaluate(policy, attrs)
+	if d.Allowed != allowed {
+		t.Errorf("policy %q: expected allowed=%t, got allowed=%t (rule %q: %s)", policy, allowed, d.Allowed, d.Rule, d.Reason)
+		return false
+	}
+
+	return true
+}
@@ -2,15 +2,21 @@ package authentication
 
 import (
 	"errors"
+	"github.com/Novometrix/util/middleware"
 	ssw "github.com/RaymondSalim/ssw-go-jwt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type authentication struct {
-	ssw ssw.SSWGoJWT
+	verifier Verifier
+
+	// newClaims returns the claims the access token is decoded into, and claimsValue the value stored in the context.
+	newClaims   func() jwt.Claims
+	claimsValue func(jwt.Claims) any
 
 	AuthenticationType
 	errorResponse          any
@@ -18,21 +24,93 @@ type authentication struct {
 	contextKey             string
 	cookieName             string
 	abortOnUnauthenticated bool
+
+	authorizationSchemes []string
+	tokenHeader          string
+	tokenQueryParam      string
+
+	tokenExtractors        []TokenExtractor
+	fallbackOnInvalidToken bool
+
+	revocationCheck      RevocationCheck
+	tokenRevokedResponse any
+
+	cookieDomain     string
+	cookiePath       string
+	cookieSameSite   http.SameSite
+	cookieInsecure   bool
+	cookieHostPrefix bool
+	cookieCodec      CookieCodec
 }
 
 type Authentication interface {
 	RequireAuthenticatedMiddleware(shouldAbortOnUnauthenticated ...bool) gin.HandlerFunc
+	// SetAccessTokenCookie sets the access token cookie the middleware reads, HttpOnly, Secure and expiring with
+	// the token, see WithCookieName and the cookie options.
+	SetAccessTokenCookie(c *gin.Context, token string, expiresAt time.Time) error
+	// ClearAccessTokenCookie clears the access token cookie, e.g. on logout.
+	ClearAccessTokenCookie(c *gin.Context)
 }
 
+// NewAuthenticationMiddleware returns an Authentication storing the claims of the access token as jwt.MapClaims.
 func NewAuthenticationMiddleware(ssw *ssw.SSWGoJWT, options ...func(*authentication)) Authentication {
+	return NewVerifierAuthenticationMiddleware(*ssw, options...)
+}
+
+// NewVerifierAuthenticationMiddleware returns an Authentication validating access tokens with v, e.g. a JWKS
+// verifier, and storing their claims as jwt.MapClaims.
+func NewVerifierAuthenticationMiddleware(v Verifier, options ...func(*authentication)) Authentication {
+	return newAuthentication(v, func() jwt.Claims {
+		return &jwt.MapClaims{}
+	}, func(claims jwt.Claims) any {
+		return *claims.(*jwt.MapClaims)
+	}, options...)
+}
+
+// NewTypedAuthenticationMiddleware returns an Authentication storing the claims of the access token as T, which
+// can be retrieved with ClaimsFrom.
+//
+//	type Claims struct {
+//		jwt.RegisteredClaims
+//		Roles []string `json:"roles"`
+//	}
+//
+//	mw := NewTypedAuthenticationMiddleware[Claims](&sswInstance)
+func NewTypedAuthenticationMiddleware[T any, PT interface {
+	*T
+	jwt.Claims
+}](ssw *ssw.SSWGoJWT, options ...func(*authentication)) Authentication {
+	return NewTypedVerifierAuthenticationMiddleware[T, PT](*ssw, options...)
+}
+
+// NewTypedVerifierAuthenticationMiddleware returns an Authentication validating access tokens with v and storing
+// their claims as T, see NewTypedAuthenticationMiddleware.
+func NewTypedVerifierAuthenticationMiddleware[T any, PT interface {
+	*T
+	jwt.Claims
+}](v Verifier, options ...func(*authentication)) Authentication {
+	return newAuthentication(v, func() jwt.Claims {
+		return PT(new(T))
+	}, func(claims jwt.Claims) any {
+		return *claims.(PT)
+	}, options...)
+}
+
+func newAuthentication(v Verifier, newClaims func() jwt.Claims, claimsValue func(jwt.Claims) any, options ...func(*authentication)) *authentication {
 	a := &authentication{
-		ssw:                    *ssw,
+		verifier:               v,
+		newClaims:              newClaims,
+		claimsValue:            claimsValue,
 		AuthenticationType:     Token,
 		errorResponse:          response{Error: http.StatusText(http.StatusUnauthorized)},
 		tokenExpiredResponse:   response{Error: TokenExpiredError.Error()},
+		tokenRevokedResponse:   response{Error: TokenRevokedError.Error()},
 		contextKey:             "user",
 		cookieName:             "access-token",
+		cookiePath:             "/",
+		cookieSameSite:         http.SameSiteLaxMode,
 		abortOnUnauthenticated: true,
+		authorizationSchemes:   []string{"Bearer"},
 	}
 
 	for _, opt := range options {
@@ -51,7 +129,9 @@ func WithErrorResponse(err any) func(*authentication) {
 // WithAuthenticationType sets the type of the authentication for the whole middleware instance
 // There are three possible authentication types.
 // For AuthenticationType == Both, access token will first be read from cookie. If it does not exist, then it will be read from the token.
-// Note that the validity of the token will not be assessed on this stage. If access token from cookie exist but is invalid, it WILL NOT continue reading from the token.
+// Note that the validity of the token will not be assessed on this stage. If access token from cookie exist but is invalid, it WILL NOT continue reading from the token,
+// unless WithFallbackOnInvalidToken is set.
+// See WithTokenExtractors for other sources and precedences.
 func WithAuthenticationType(t AuthenticationType) func(*authentication) {
 	return func(a *authentication) {
 		a.AuthenticationType = t
@@ -82,43 +162,94 @@ func WithAbortOnUnauthenticated(ab bool) func(*authentication) {
 	}
 }
 
+// WithAuthorizationSchemes sets the schemes accepted in the Authorization header, compared case-insensitively.
+// Defaults to "Bearer".
+func WithAuthorizationSchemes(schemes ...string) func(*authentication) {
+	return func(a *authentication) {
+		a.authorizationSchemes = schemes
+	}
+}
+
+// WithTokenHeader sets a header the access token is read from, without scheme, if the Authorization header has none.
+func WithTokenHeader(h string) func(*authentication) {
+	return func(a *authentication) {
+		a.tokenHeader = h
+	}
+}
+
+// WithTokenQueryParam sets a query parameter the access token is read from if no header has one, e.g. for
+// websocket connections which cannot set headers. Note that URLs, and so the token, commonly end up in logs.
+func WithTokenQueryParam(p string) func(*authentication) {
+	return func(a *authentication) {
+		a.tokenQueryParam = p
+	}
+}
+
+// WithTokenExtractors sets the extractors the access token is read with, in order of precedence. The first token
+// found is validated. It replaces the sources of the AuthenticationType, WithTokenHeader and WithTokenQueryParam.
+//
+//	WithTokenExtractors(FromHeader("Authorization", "Bearer"), FromCookie("access-token"))
+func WithTokenExtractors(extractors ...TokenExtractor) func(*authentication) {
+	return func(a *authentication) {
+		a.tokenExtractors = extractors
+	}
+}
+
+// WithFallbackOnInvalidToken sets whether the next extractor is tried when the token of an earlier one fails
+// validation. The error of the last token found is responded with if none is valid.
+func WithFallbackOnInvalidToken(f bool) func(*authentication) {
+	return func(a *authentication) {
+		a.fallbackOnInvalidToken = f
+	}
+}
+
+// WithRevocationCheck sets the check the validated claims are rejected with if revoked, e.g. Revocation.IsRevoked.
+// Tokens are rejected as well if the check fails.
+func WithRevocationCheck(check RevocationCheck) func(*authentication) {
+	return func(a *authentication) {
+		a.revocationCheck = check
+	}
+}
+
+func WithTokenRevokedResponse(r any) func(*authentication) {
+	return func(a *authentication) {
+		a.tokenRevokedResponse = r
+	}
+}
+
 func (a authentication) RequireAuthenticatedMiddleware(shouldAbortOnUnauthenticated ...bool) gin.HandlerFunc {
 	abort := a.abortOnUnauthenticated
 	if len(shouldAbortOnUnauthenticated) > 0 {
 		abort = shouldAbortOnUnauthenticated[0]
 	}
 
+	extractors := a.extractors()
+
 	return func(c *gin.Context) {
-		getAccessTokenFromCookie := func() string {
-			atCookie, _ := c.Cookie(a.cookieName)
-			return atCookie
-		}
+		var (
+			found  bool
+			source TokenSource
+			claims jwt.Claims
+			err    error
+		)
+		for _, extract := range extractors {
+			at, s := extract(c)
+			if len(at) == 0 {
+				continue
+			}
 
-		getAccessTokenFromToken := func() string {
-			authHeader := c.Request.Header.Get("Authorization")
-			t := strings.Split(authHeader, " ")
-			if len(t) == 2 && t[1] != "" {
-				return t[1]
+			found, source = true, s
+			claims = a.newClaims()
+			err = a.verifier.ValidateAccessTokenWithClaims(at, claims)
+			if err == nil {
+				err = a.checkRevocation(c, claims)
 			}
-			return ""
-		}
-		var at string
-
-		switch a.AuthenticationType {
-		case Cookie:
-			at = getAccessTokenFromCookie()
-		case Token:
-			at = getAccessTokenFromToken()
-		case Both:
-			atCookie := getAccessTokenFromCookie()
-			if len(atCookie) > 0 {
-				at = atCookie
+			if err == nil || !a.fallbackOnInvalidToken {
 				break
 			}
-			at = getAccessTokenFromToken()
 		}
 
-		if len(at) == 0 {
+		if !found {
 			if abort {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, a.errorResponse)
 				return
@@ -128,12 +259,12 @@ func (a authentication) RequireAuthenticatedMiddleware(shouldAbortOnUnauthentica
 			return
 		}
 
-		claims := &jwt.MapClaims{}
-		err := a.ssw.ValidateAccessTokenWithClaims(at, claims)
 		if err != nil {
 			if abort {
 				if errors.Is(err, jwt.ErrTokenExpired) {
 					c.AbortWithStatusJSON(http.StatusUnauthorized, a.tokenExpiredResponse)
+				} else if errors.Is(err, TokenRevokedError) {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, a.tokenRevokedResponse)
 				} else {
 					c.AbortWithStatusJSON(http.StatusUnauthorized, a.errorResponse)
 				}
@@ -144,7 +275,118 @@ func (a authentication) RequireAuthenticatedMiddleware(shouldAbortOnUnauthentica
 			return
 		}
 
-		c.Set(a.contextKey, *claims)
+		v := a.claimsValue(claims)
+		c.Set(a.contextKey, v)
+		c.Set(claimsContextKey, v)
+		c.Set(tokenSourceContextKey, source)
 		c.Next()
 	}
 }
+
+// checkRevocation returns TokenRevokedError if the claims are revoked.
+func (a authentication) checkRevocation(c *gin.Context, claims jwt.Claims) error {
+	if a.revocationCheck == nil {
+		return nil
+	}
+
+	revoked, err := a.revocationCheck(c, claims)
+	if err != nil {
+		middleware.LoggerFrom(c).Errorf("failed to check access token revocation with error: %v", err)
+		return err
+	}
+	if revoked {
+		return TokenRevokedError
+	}
+
+	return nil
+}
+
+// extractors returns the configured token extractors, or the ones of the AuthenticationType.
+func (a authentication) extractors() []TokenExtractor {
+	if len(a.tokenExtractors) > 0 {
+		return a.tokenExtractors
+	}
+
+	token := []TokenExtractor{FromHeader("Authorization", a.authorizationSchemes...)}
+	if a.tokenHeader != "" {
+		token = append(token, FromHeader(a.tokenHeader))
+	}
+	if a.tokenQueryParam != "" {
+		token = append(token, FromQuery(a.tokenQueryParam))
+	}
+
+	switch a.AuthenticationType {
+	case Cookie:
+		return []TokenExtractor{a.cookieExtractor()}
+	case Both:
+		return append([]TokenExtractor{a.cookieExtractor()}, token...)
+	default:
+		return token
+	}
+}
+
+// TokenSourceFrom returns the source of the access token the request was authenticated with.
+func TokenSourceFrom(c *gin.Context) (TokenSource, bool) {
+	v, exists := c.Get(tokenSourceContextKey)
+	if !exists {
+		return "", false
+	}
+
+	source, ok := v.(TokenSource)
+	return source, ok
+}
+
+// ClaimsFrom returns the claims stored by the authentication middleware, regardless of its context key.
+// T must be the claims type of the middleware: jwt.MapClaims for NewAuthenticationMiddleware, or the type parameter
+// of NewTypedAuthenticationMiddleware.
+func ClaimsFrom[T any](c *gin.Context) (T, bool) {
+	v, exists := c.Get(claimsContextKey)
+	if !exists {
+		var zero T
+		return zero, false
+	}
+
+	claims, ok := v.(T)
+	return claims, ok
+}
+
+// parseAuthorizationHeader returns the credentials of an Authorization header with one of the schemes, following
+// RFC 6750: the scheme is case-insensitive, the credentials must be a token68 and surrounding whitespace is ignored.
+func parseAuthorizationHeader(h string, schemes []string) string {
+	h = strings.TrimSpace(h)
+	i := strings.IndexAny(h, " \t")
+	if i < 0 {
+		return ""
+	}
+
+	scheme, token := h[:i], strings.TrimSpace(h[i+1:])
+	for _, s := range schemes {
+		if strings.EqualFold(scheme, s) {
+			if isToken68(token) {
+				return token
+			}
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// isToken68 reports whether t is a token68 as defined by RFC 7235, which includes base64url encoded JWTs.
+func isToken68(t string) bool {
+	t = strings.TrimRight(t, "=")
+	if t == "" {
+		return false
+	}
+
+	for _, r := range t {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case r == '-', r == '.', r == '_', r == '~', r == '+', r == '/':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
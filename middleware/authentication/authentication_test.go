@@ -589,6 +589,51 @@ func TestFunctionalOptions(t *testing.T) {
 				assert.EqualValues(t, false, m.abortOnUnauthenticated)
 			},
 		},
+		{
+			name: "success_WithAuthorizationSchemes",
+			run: func(t *testing.T) {
+				m := &authentication{}
+				WithAuthorizationSchemes("Bearer", "DPoP")(m)
+
+				assert.EqualValues(t, []string{"Bearer", "DPoP"}, m.authorizationSchemes)
+			},
+		},
+		{
+			name: "success_WithTokenHeader",
+			run: func(t *testing.T) {
+				m := &authentication{}
+				WithTokenHeader(testString)(m)
+
+				assert.EqualValues(t, testString, m.tokenHeader)
+			},
+		},
+		{
+			name: "success_WithTokenQueryParam",
+			run: func(t *testing.T) {
+				m := &authentication{}
+				WithTokenQueryParam(testString)(m)
+
+				assert.EqualValues(t, testString, m.tokenQueryParam)
+			},
+		},
+		{
+			name: "success_WithTokenExtractors",
+			run: func(t *testing.T) {
+				m := &authentication{}
+				WithTokenExtractors(FromCookie(testString), FromQuery(testString))(m)
+
+				assert.Len(t, m.tokenExtractors, 2)
+			},
+		},
+		{
+			name: "success_WithFallbackOnInvalidToken",
+			run: func(t *testing.T) {
+				m := &authentication{}
+				WithFallbackOnInvalidToken(true)(m)
+
+				assert.EqualValues(t, true, m.fallbackOnInvalidToken)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -597,3 +642,296 @@ func TestFunctionalOptions(t *testing.T) {
 		})
 	}
 }
+
+type testClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+func TestNewTypedAuthenticationMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		mock   func(t *testing.T, sswMock *ssw.MockSSWGoJWT)
+		verify func(t *testing.T, w *httptest.ResponseRecorder, claims testClaims, exists bool)
+	}{
+		{
+			name: "success",
+			mock: func(t *testing.T, sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*authentication.testClaims")).Return(nil).Run(func(fArg mock.Arguments) {
+					claims := fArg.Get(1).(*testClaims)
+					claims.Subject = testUsername
+					claims.Roles = []string{testString}
+				}).Once()
+			},
+			verify: func(t *testing.T, w *httptest.ResponseRecorder, claims testClaims, exists bool) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.True(t, exists)
+				assert.Equal(t, testUsername, claims.Subject)
+				assert.Equal(t, []string{testString}, claims.Roles)
+			},
+		},
+		{
+			name: "error_invalid_token",
+			mock: func(t *testing.T, sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*authentication.testClaims")).Return(testError).Once()
+			},
+			verify: func(t *testing.T, w *httptest.ResponseRecorder, claims testClaims, exists bool) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.False(t, exists)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sswMock := ssw.NewMockSSWGoJWT(t)
+			tt.mock(t, sswMock)
+
+			var sswInstance ssw.SSWGoJWT = sswMock
+			mw := NewTypedAuthenticationMiddleware[testClaims](&sswInstance)
+
+			var claims testClaims
+			var exists bool
+
+			r := gin.New()
+			r.Use(mw.RequireAuthenticatedMiddleware())
+			r.GET("/", func(c *gin.Context) {
+				claims, exists = ClaimsFrom[testClaims](c)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+testJWTString)
+			r.ServeHTTP(w, req)
+
+			tt.verify(t, w, claims, exists)
+		})
+	}
+}
+
+func TestClaimsFrom(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, exists := ClaimsFrom[jwt.MapClaims](c)
+	assert.False(t, exists)
+
+	c.Set(claimsContextKey, jwt.MapClaims{"sub": testUsername})
+
+	claims, exists := ClaimsFrom[jwt.MapClaims](c)
+	assert.True(t, exists)
+	assert.Equal(t, testUsername, claims["sub"])
+
+	_, exists = ClaimsFrom[testClaims](c)
+	assert.False(t, exists)
+}
+
+func TestParseAuthorizationHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		schemes []string
+		want    string
+	}{
+		{name: "bearer", header: "Bearer " + testJWTString, schemes: []string{"Bearer"}, want: testJWTString},
+		{name: "lowercase scheme", header: "bearer " + testJWTString, schemes: []string{"Bearer"}, want: testJWTString},
+		{name: "extra whitespace", header: "  Bearer \t  " + testJWTString + " ", schemes: []string{"Bearer"}, want: testJWTString},
+		{name: "padding", header: "Bearer dGVzdA==", schemes: []string{"Bearer"}, want: "dGVzdA=="},
+		{name: "other scheme", header: "Basic dXNlcjpwYXNz", schemes: []string{"Bearer"}},
+		{name: "configured scheme", header: "DPoP " + testJWTString, schemes: []string{"Bearer", "DPoP"}, want: testJWTString},
+		{name: "no scheme", header: testJWTString, schemes: []string{"Bearer"}},
+		{name: "no token", header: "Bearer ", schemes: []string{"Bearer"}},
+		{name: "whitespace in token", header: "Bearer " + testString, schemes: []string{"Bearer"}},
+		{name: "invalid character", header: "Bearer abc,def", schemes: []string{"Bearer"}},
+		{name: "padding only", header: "Bearer ==", schemes: []string{"Bearer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAuthorizationHeader(tt.header, tt.schemes))
+		})
+	}
+}
+
+func TestTokenSources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		options []func(*authentication)
+		setup   func(req *http.Request)
+		wantOK  bool
+	}{
+		{
+			name: "authorization header",
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", "BEARER  "+testJWTString)
+			},
+			wantOK: true,
+		},
+		{
+			name: "unaccepted scheme",
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", "Foo "+testJWTString)
+			},
+		},
+		{
+			name:    "custom header",
+			options: []func(*authentication){WithTokenHeader("X-Access-Token")},
+			setup: func(req *http.Request) {
+				req.Header.Set("X-Access-Token", testJWTString)
+			},
+			wantOK: true,
+		},
+		{
+			name:    "query param",
+			options: []func(*authentication){WithTokenQueryParam("access_token")},
+			setup: func(req *http.Request) {
+				req.URL.RawQuery = "access_token=" + testJWTString
+			},
+			wantOK: true,
+		},
+		{
+			name: "query param not configured",
+			setup: func(req *http.Request) {
+				req.URL.RawQuery = "access_token=" + testJWTString
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sswMock := ssw.NewMockSSWGoJWT(t)
+			if tt.wantOK {
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+			}
+
+			var goJWT ssw.SSWGoJWT = sswMock
+			mw := NewAuthenticationMiddleware(&goJWT, tt.options...)
+
+			r := gin.New()
+			r.Use(mw.RequireAuthenticatedMiddleware())
+			r.GET("/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.setup(req)
+			r.ServeHTTP(w, req)
+
+			if tt.wantOK {
+				assert.Equal(t, http.StatusOK, w.Code)
+			} else {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
+
+func TestTokenExtractorChain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const cookieToken = "cookie.token.value"
+
+	tests := []struct {
+		name       string
+		options    []func(*authentication)
+		mock       func(sswMock *ssw.MockSSWGoJWT)
+		wantStatus int
+		wantSource TokenSource
+	}{
+		{
+			name: "header before cookie",
+			options: []func(*authentication){
+				WithTokenExtractors(FromHeader("Authorization", "Bearer"), FromCookie(cookieStr)),
+			},
+			mock: func(sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantSource: SourceHeader,
+		},
+		{
+			name: "invalid cookie blocks header",
+			options: []func(*authentication){
+				WithAuthenticationType(Both), WithCookieName(cookieStr),
+			},
+			mock: func(sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", cookieToken, mock.AnythingOfType("*jwt.MapClaims")).Return(testError).Once()
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid cookie falls back to header",
+			options: []func(*authentication){
+				WithAuthenticationType(Both), WithCookieName(cookieStr), WithFallbackOnInvalidToken(true),
+			},
+			mock: func(sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", cookieToken, mock.AnythingOfType("*jwt.MapClaims")).Return(testError).Once()
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantSource: SourceHeader,
+		},
+		{
+			name: "all invalid responds with last error",
+			options: []func(*authentication){
+				WithTokenExtractors(FromCookie(cookieStr), FromHeader("Authorization", "Bearer")),
+				WithFallbackOnInvalidToken(true),
+			},
+			mock: func(sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", cookieToken, mock.AnythingOfType("*jwt.MapClaims")).Return(testError).Once()
+				sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(jwt.ErrTokenExpired).Once()
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "custom extractor",
+			options: []func(*authentication){
+				WithTokenExtractors(func(c *gin.Context) (string, TokenSource) {
+					return c.GetHeader("X-Custom"), "custom"
+				}),
+			},
+			mock: func(sswMock *ssw.MockSSWGoJWT) {
+				sswMock.On("ValidateAccessTokenWithClaims", testString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+			},
+			wantStatus: http.StatusOK,
+			wantSource: "custom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sswMock := ssw.NewMockSSWGoJWT(t)
+			tt.mock(sswMock)
+
+			var goJWT ssw.SSWGoJWT = sswMock
+			mw := NewAuthenticationMiddleware(&goJWT, tt.options...)
+
+			r := gin.New()
+			r.Use(mw.RequireAuthenticatedMiddleware())
+			r.GET("/", func(c *gin.Context) {
+				source, _ := TokenSourceFrom(c)
+				c.String(http.StatusOK, "%s", source)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+testJWTString)
+			req.Header.Set("X-Custom", testString)
+			req.AddCookie(&http.Cookie{Name: cookieStr, Value: cookieToken})
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, string(tt.wantSource), w.Body.String())
+			} else {
+				assert.NotEqual(t, string(SourceHeader), w.Body.String())
+			}
+		})
+	}
+}
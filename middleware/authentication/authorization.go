@@ -0,0 +1,201 @@
+package authentication
+
+import (
+	"encoding/json"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+)
+
+// Policy decides whether the authenticated request is authorized.
+type Policy func(c *gin.Context, claims jwt.MapClaims) bool
+
+type authorization struct {
+	scopeClaim              string
+	rolesClaims             []string
+	forbiddenResponse       any
+	unauthenticatedResponse any
+}
+
+type Authorization interface {
+	// RequireScopes authorizes requests whose claims have all the scopes.
+	RequireScopes(scopes ...string) gin.HandlerFunc
+	// RequireAnyRole authorizes requests whose claims have at least one of the roles.
+	RequireAnyRole(roles ...string) gin.HandlerFunc
+	// RequireAllRoles authorizes requests whose claims have all the roles.
+	RequireAllRoles(roles ...string) gin.HandlerFunc
+	// RequirePolicy authorizes requests for which the policy returns true.
+	RequirePolicy(policy Policy) gin.HandlerFunc
+}
+
+// NewAuthorizationMiddleware returns an Authorization checking the claims stored by the authentication middleware,
+// which must run first. Requests without claims are aborted with 401, unauthorized requests with 403.
+func NewAuthorizationMiddleware(options ...func(*authorization)) Authorization {
+	a := &authorization{
+		scopeClaim:              "scope",
+		rolesClaims:             []string{"roles", "realm_access.roles"},
+		forbiddenResponse:       response{Error: http.StatusText(http.StatusForbidden)},
+		unauthenticatedResponse: response{Error: http.StatusText(http.StatusUnauthorized)},
+	}
+
+	for _, opt := range options {
+		opt(a)
+	}
+
+	return a
+}
+
+// WithScopeClaim sets the name of the claim holding the scopes, either as a space-delimited string or an array.
+// Defaults to "scope".
+func WithScopeClaim(name string) func(*authorization) {
+	return func(a *authorization) {
+		a.scopeClaim = name
+	}
+}
+
+// WithRolesClaims sets the names of the claims holding the roles. Nested claims are separated by dots.
+// Defaults to "roles" and "realm_access.roles".
+func WithRolesClaims(names ...string) func(*authorization) {
+	return func(a *authorization) {
+		a.rolesClaims = names
+	}
+}
+
+func WithForbiddenResponse(r any) func(*authorization) {
+	return func(a *authorization) {
+		a.forbiddenResponse = r
+	}
+}
+
+func WithUnauthenticatedResponse(r any) func(*authorization) {
+	return func(a *authorization) {
+		a.unauthenticatedResponse = r
+	}
+}
+
+func (a authorization) RequireScopes(scopes ...string) gin.HandlerFunc {
+	return a.RequirePolicy(func(c *gin.Context, claims jwt.MapClaims) bool {
+		return containsAll(claimValues(claims, a.scopeClaim), scopes)
+	})
+}
+
+func (a authorization) RequireAnyRole(roles ...string) gin.HandlerFunc {
+	return a.RequirePolicy(func(c *gin.Context, claims jwt.MapClaims) bool {
+		r := a.roles(claims)
+		for _, role := range roles {
+			if util.SliceContains(r, role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (a authorization) RequireAllRoles(roles ...string) gin.HandlerFunc {
+	return a.RequirePolicy(func(c *gin.Context, claims jwt.MapClaims) bool {
+		return containsAll(a.roles(claims), roles)
+	})
+}
+
+func (a authorization) RequirePolicy(policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := mapClaimsFrom(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, a.unauthenticatedResponse)
+			return
+		}
+
+		if !policy(c, claims) {
+			c.AbortWithStatusJSON(http.StatusForbidden, a.forbiddenResponse)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (a authorization) roles(claims jwt.MapClaims) []string {
+	var roles []string
+	for _, name := range a.rolesClaims {
+		roles = append(roles, claimValues(claims, name)...)
+	}
+
+	return roles
+}
+
+// mapClaimsFrom returns the claims stored by the authentication middleware as jwt.MapClaims,
+// converting typed claims through their JSON representation.
+func mapClaimsFrom(c *gin.Context) (jwt.MapClaims, bool) {
+	v, exists := c.Get(claimsContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	if claims, ok := v.(jwt.MapClaims); ok {
+		return claims, true
+	}
+
+	m, err := toMap(v)
+	if err != nil {
+		return nil, false
+	}
+
+	return m, true
+}
+
+// toMap returns v as a map through its JSON representation.
+func toMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]any{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// claimValues returns the values of the claim at the dot-separated path, which is either a space-delimited string
+// or an array of strings.
+func claimValues(claims jwt.MapClaims, path string) []string {
+	var v any = map[string]any(claims)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func containsAll(values, expected []string) bool {
+	for _, e := range expected {
+		if !util.SliceContains(values, e) {
+			return false
+		}
+	}
+
+	return true
+}
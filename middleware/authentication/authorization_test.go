@@ -0,0 +1,132 @@
+package authentication
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mapClaims := jwt.MapClaims{
+		"sub":   testUsername,
+		"scope": "read write",
+		"roles": []any{"editor"},
+		"realm_access": map[string]any{
+			"roles": []any{"admin"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		options    []func(*authorization)
+		claims     any
+		handler    func(a Authorization) gin.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "scopes granted",
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireScopes("read", "write") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "scope missing",
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireScopes("read", "delete") },
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"error":"Forbidden"}`,
+		},
+		{
+			name:       "scopes from custom array claim",
+			options:    []func(*authorization){WithScopeClaim("scp")},
+			claims:     jwt.MapClaims{"scp": []any{"read"}},
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireScopes("read") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "any role from nested claim",
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireAnyRole("viewer", "admin") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no matching role",
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireAnyRole("viewer") },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "all roles across claims",
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireAllRoles("editor", "admin") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "nested roles ignored when not configured",
+			options:    []func(*authorization){WithRolesClaims("roles")},
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireAllRoles("editor", "admin") },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "typed claims",
+			claims:     testClaims{Roles: []string{"admin"}},
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireAnyRole("admin") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "policy",
+			claims: mapClaims,
+			handler: func(a Authorization) gin.HandlerFunc {
+				return a.RequirePolicy(func(c *gin.Context, claims jwt.MapClaims) bool {
+					return claims["sub"] == c.Param("user")
+				})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "custom forbidden response",
+			options:    []func(*authorization){WithForbiddenResponse(response{Error: testString})},
+			claims:     mapClaims,
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireScopes("delete") },
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"error":"` + testString + `"}`,
+		},
+		{
+			name:       "unauthenticated",
+			handler:    func(a Authorization) gin.HandlerFunc { return a.RequireScopes("read") },
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"Unauthorized"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthorizationMiddleware(tt.options...)
+
+			r := gin.New()
+			r.GET("/users/:user", func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set(claimsContextKey, tt.claims)
+				}
+			}, tt.handler(a), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+testUsername, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -8,6 +8,27 @@ const (
 	Both
 )
 
+const (
+	SourceHeader TokenSource = "header"
+	SourceCookie TokenSource = "cookie"
+	SourceQuery  TokenSource = "query"
+	SourceForm   TokenSource = "form"
+)
+
+const (
+	claimsContextKey      = "github.com/Novometrix/util/middleware/authentication.claims"
+	tokenSourceContextKey = "github.com/Novometrix/util/middleware/authentication.tokenSource"
+)
+
 var (
-	TokenExpiredError = errors.New("access token expired")
+	TokenExpiredError     = errors.New("access token expired")
+	TokenRevokedError     = errors.New("access token revoked")
+	InvalidPolicyError    = errors.New("invalid policy")
+	InvalidCookieError    = errors.New("invalid cookie")
+	InvalidCSRFTokenError = errors.New("invalid CSRF token")
+	StoreNotListableError = errors.New("revocation store cannot list its keys")
+
+	RefreshTokenNotFoundError = errors.New("refresh token not found")
+	RefreshTokenExpiredError  = errors.New("refresh token expired")
+	RefreshTokenReusedError   = errors.New("refresh token reused")
 )
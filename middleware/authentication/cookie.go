@@ -0,0 +1,230 @@
+package authentication
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const hostCookiePrefix = "__Host-"
+
+// CookieCodec encodes the values of cookies, e.g. to sign or encrypt them. The name of the cookie is bound to the
+// value, so a value cannot be moved to another cookie.
+type CookieCodec interface {
+	Encode(name, value string) (string, error)
+	// Decode returns the value of an encoded cookie, or InvalidCookieError if it was tampered with.
+	Decode(name, value string) (string, error)
+}
+
+type signingCookieCodec struct {
+	key []byte
+}
+
+// NewSigningCookieCodec returns a CookieCodec appending an HMAC-SHA256 signature to the values.
+func NewSigningCookieCodec(key []byte) CookieCodec {
+	return signingCookieCodec{key: key}
+}
+
+func (s signingCookieCodec) Encode(name, value string) (string, error) {
+	return value + "." + s.sign(name, value), nil
+}
+
+func (s signingCookieCodec) Decode(name, value string) (string, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", InvalidCookieError
+	}
+
+	value, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(name, value))) {
+		return "", InvalidCookieError
+	}
+
+	return value, nil
+}
+
+func (s signingCookieCodec) sign(name, value string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(name + "=" + value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type encryptingCookieCodec struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptingCookieCodec returns a CookieCodec encrypting the values with AES-GCM. The key must be 16, 24 or 32
+// bytes long.
+func NewEncryptingCookieCodec(key []byte) (CookieCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptingCookieCodec{aead: aead}, nil
+}
+
+func (e encryptingCookieCodec) Encode(name, value string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (e encryptingCookieCodec) Decode(name, value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < e.aead.NonceSize() {
+		return "", InvalidCookieError
+	}
+
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	b, err := e.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", InvalidCookieError
+	}
+
+	return string(b), nil
+}
+
+// FromEncodedCookie extracts the access token of a cookie encoded with codec. Cookies failing to decode are ignored.
+func FromEncodedCookie(name string, codec CookieCodec) TokenExtractor {
+	return func(c *gin.Context) (string, TokenSource) {
+		v, _ := c.Cookie(name)
+		if v == "" {
+			return "", SourceCookie
+		}
+
+		t, err := codec.Decode(name, v)
+		if err != nil {
+			return "", SourceCookie
+		}
+		return t, SourceCookie
+	}
+}
+
+// WithCookieDomain sets the domain of the access token cookie. Defaults to none, restricting it to the host.
+func WithCookieDomain(d string) func(*authentication) {
+	return func(a *authentication) {
+		a.cookieDomain = d
+	}
+}
+
+// WithCookiePath sets the path of the access token cookie. Defaults to "/".
+func WithCookiePath(p string) func(*authentication) {
+	return func(a *authentication) {
+		a.cookiePath = p
+	}
+}
+
+// WithCookieSameSite sets the SameSite attribute of the access token cookie. Defaults to http.SameSiteLaxMode.
+func WithCookieSameSite(s http.SameSite) func(*authentication) {
+	return func(a *authentication) {
+		a.cookieSameSite = s
+	}
+}
+
+// WithInsecureCookie drops the Secure attribute of the access token cookie, e.g. for local development over http.
+func WithInsecureCookie(insecure bool) func(*authentication) {
+	return func(a *authentication) {
+		a.cookieInsecure = insecure
+	}
+}
+
+// WithHostPrefixCookie prefixes the name of the access token cookie with "__Host-", which browsers only accept for
+// Secure cookies with the path "/" and no domain, so the cookie cannot be set by subdomains. The path and domain
+// options are ignored.
+func WithHostPrefixCookie(prefix bool) func(*authentication) {
+	return func(a *authentication) {
+		a.cookieHostPrefix = prefix
+	}
+}
+
+// WithCookieCodec sets the codec the access token cookie is encoded with, see NewSigningCookieCodec and
+// NewEncryptingCookieCodec.
+func WithCookieCodec(codec CookieCodec) func(*authentication) {
+	return func(a *authentication) {
+		a.cookieCodec = codec
+	}
+}
+
+func (a authentication) SetAccessTokenCookie(c *gin.Context, token string, expiresAt time.Time) error {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge <= 0 {
+		a.ClearAccessTokenCookie(c)
+		return nil
+	}
+
+	if a.cookieCodec != nil {
+		var err error
+		if token, err = a.cookieCodec.Encode(a.accessTokenCookieName(), token); err != nil {
+			return err
+		}
+	}
+
+	cookie := a.accessTokenCookie(token)
+	cookie.MaxAge = maxAge
+	cookie.Expires = expiresAt.UTC()
+	http.SetCookie(c.Writer, cookie)
+
+	return nil
+}
+
+func (a authentication) ClearAccessTokenCookie(c *gin.Context) {
+	cookie := a.accessTokenCookie("")
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(c.Writer, cookie)
+}
+
+func (a authentication) accessTokenCookie(value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     a.accessTokenCookieName(),
+		Value:    value,
+		Path:     a.cookiePath,
+		Domain:   a.cookieDomain,
+		Secure:   !a.cookieInsecure,
+		HttpOnly: true,
+		SameSite: a.cookieSameSite,
+	}
+	if a.cookieHostPrefix {
+		cookie.Path = "/"
+		cookie.Domain = ""
+		cookie.Secure = true
+	}
+
+	return cookie
+}
+
+// accessTokenCookieName returns the name of the access token cookie, with the "__Host-" prefix if enabled.
+func (a authentication) accessTokenCookieName() string {
+	if a.cookieHostPrefix && !strings.HasPrefix(a.cookieName, hostCookiePrefix) {
+		return hostCookiePrefix + a.cookieName
+	}
+
+	return a.cookieName
+}
+
+// cookieExtractor returns the extractor of the access token cookie.
+func (a authentication) cookieExtractor() TokenExtractor {
+	if a.cookieCodec != nil {
+		return FromEncodedCookie(a.accessTokenCookieName(), a.cookieCodec)
+	}
+
+	return FromCookie(a.accessTokenCookieName())
+}
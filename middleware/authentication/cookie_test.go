@@ -0,0 +1,209 @@
+package authentication
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ssw "github.com/RaymondSalim/ssw-go-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testCookieKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestCookieCodecs(t *testing.T) {
+	encrypting, err := NewEncryptingCookieCodec(testCookieKey)
+	assert.NoError(t, err)
+
+	_, err = NewEncryptingCookieCodec([]byte("short"))
+	assert.Error(t, err)
+
+	codecs := map[string]CookieCodec{
+		"signing":    NewSigningCookieCodec(testCookieKey),
+		"encrypting": encrypting,
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := codec.Encode(cookieStr, testJWTString)
+			assert.NoError(t, err)
+
+			decoded, err := codec.Decode(cookieStr, encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, testJWTString, decoded)
+
+			_, err = codec.Decode("other", encoded)
+			assert.True(t, errors.Is(err, InvalidCookieError))
+
+			_, err = codec.Decode(cookieStr, encoded[:len(encoded)-2]+"xx")
+			assert.True(t, errors.Is(err, InvalidCookieError))
+
+			_, err = codec.Decode(cookieStr, testJWTString)
+			assert.True(t, errors.Is(err, InvalidCookieError))
+		})
+	}
+}
+
+func newCookieAuthentication(t *testing.T, options ...func(*authentication)) (Authentication, *ssw.MockSSWGoJWT) {
+	sswMock := ssw.NewMockSSWGoJWT(t)
+	var goJWT ssw.SSWGoJWT = sswMock
+
+	return NewAuthenticationMiddleware(&goJWT, options...), sswMock
+}
+
+func TestSetAccessTokenCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		options []func(*authentication)
+		verify  func(t *testing.T, cookie *http.Cookie)
+	}{
+		{
+			name:    "defaults",
+			options: []func(*authentication){WithCookieName(cookieStr)},
+			verify: func(t *testing.T, cookie *http.Cookie) {
+				assert.Equal(t, cookieStr, cookie.Name)
+				assert.Equal(t, testJWTString, cookie.Value)
+				assert.Equal(t, "/", cookie.Path)
+				assert.Empty(t, cookie.Domain)
+				assert.True(t, cookie.HttpOnly)
+				assert.True(t, cookie.Secure)
+				assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+				assert.InDelta(t, time.Hour.Seconds(), cookie.MaxAge, 5)
+			},
+		},
+		{
+			name: "attributes",
+			options: []func(*authentication){
+				WithCookieName(cookieStr), WithCookieDomain("example.com"), WithCookiePath("/api"),
+				WithCookieSameSite(http.SameSiteStrictMode), WithInsecureCookie(true),
+			},
+			verify: func(t *testing.T, cookie *http.Cookie) {
+				assert.Equal(t, "example.com", cookie.Domain)
+				assert.Equal(t, "/api", cookie.Path)
+				assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+				assert.False(t, cookie.Secure)
+			},
+		},
+		{
+			name: "host prefix",
+			options: []func(*authentication){
+				WithCookieName(cookieStr), WithHostPrefixCookie(true), WithCookieDomain("example.com"),
+				WithCookiePath("/api"), WithInsecureCookie(true),
+			},
+			verify: func(t *testing.T, cookie *http.Cookie) {
+				assert.Equal(t, "__Host-"+cookieStr, cookie.Name)
+				assert.Empty(t, cookie.Domain)
+				assert.Equal(t, "/", cookie.Path)
+				assert.True(t, cookie.Secure)
+			},
+		},
+		{
+			name:    "signed",
+			options: []func(*authentication){WithCookieName(cookieStr), WithCookieCodec(NewSigningCookieCodec(testCookieKey))},
+			verify: func(t *testing.T, cookie *http.Cookie) {
+				assert.NotEqual(t, testJWTString, cookie.Value)
+				assert.Contains(t, cookie.Value, testJWTString)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newCookieAuthentication(t, tt.options...)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			assert.NoError(t, a.SetAccessTokenCookie(c, testJWTString, expiresAt))
+
+			cookies := w.Result().Cookies()
+			if assert.Len(t, cookies, 1) {
+				tt.verify(t, cookies[0])
+			}
+		})
+	}
+}
+
+func TestClearAccessTokenCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	a, _ := newCookieAuthentication(t, WithCookieName(cookieStr))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	a.ClearAccessTokenCookie(c)
+
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, cookieStr, cookies[0].Name)
+		assert.Empty(t, cookies[0].Value)
+		assert.Less(t, cookies[0].MaxAge, 0)
+	}
+
+	// An expired token clears the cookie.
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	assert.NoError(t, a.SetAccessTokenCookie(c, testJWTString, time.Now().Add(-time.Minute)))
+
+	cookies = w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Empty(t, cookies[0].Value)
+	}
+}
+
+func TestEncryptedCookieAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	codec, err := NewEncryptingCookieCodec(testCookieKey)
+	assert.NoError(t, err)
+
+	a, sswMock := newCookieAuthentication(t, WithAuthenticationType(Cookie), WithCookieName(cookieStr),
+		WithHostPrefixCookie(true), WithCookieCodec(codec))
+	sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	assert.NoError(t, a.SetAccessTokenCookie(c, testJWTString, time.Now().Add(time.Hour)))
+	cookie := w.Result().Cookies()[0]
+
+	r := gin.New()
+	r.Use(a.RequireAuthenticatedMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A plain token in the cookie is ignored.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: testJWTString})
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTokenRotationAuthenticationCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	a, _ := newCookieAuthentication(t, WithCookieName(cookieStr), WithHostPrefixCookie(true))
+	r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore(), WithRotationAuthentication(a)))
+
+	w := serveRotation(r, "/login")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	access := responseCookie(w, "__Host-"+cookieStr)
+	if assert.NotNil(t, access) {
+		assert.Equal(t, testJWTString, access.Value)
+	}
+}
@@ -0,0 +1,294 @@
+package authentication
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/Novometrix/util/middleware"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSRFHeader = "X-CSRF-Token"
+
+	csrfTokenContextKey = "github.com/Novometrix/util/middleware/authentication.csrfToken"
+)
+
+type csrf struct {
+	// secret enables the synchronizer token mode, in which tokens are signed for the subject instead of being
+	// submitted twice.
+	secret []byte
+	ttl    time.Duration
+
+	cookieName     string
+	formField      string
+	trustedOrigins []string
+	requireOrigin  bool
+	// protectedCookies are the cookies which, when sent, subject a request to the checks even if it is not
+	// authenticated with a cookie, e.g. the refresh token cookie of TokenRotation.
+	protectedCookies []string
+	errorResponse    any
+}
+
+// CSRFMiddleware protects the unsafe methods of requests authenticated with a cookie against cross-site request
+// forgery. Requests authenticated with a header, query or form token, which browsers do not send on their own, are
+// left alone, so the middleware must be registered after the authentication middleware. Requests sending the
+// refresh token cookie of TokenRotation are protected as well, see WithCSRFProtectedCookies.
+//
+// Unsafe requests must come from the origin of the request or a trusted one, by their Origin or Referer header, and
+// carry the CSRF token in the X-CSRF-Token header or the "csrf_token" form field. By default, the token is the value
+// of a random "csrf-token" cookie readable by scripts, which cross-site requests can send but not read (double
+// submit). With WithCSRFSynchronizerToken, the token is signed for the authenticated subject instead.
+// Safe requests are given the token, see CSRFTokenFrom, also written to the X-CSRF-Token response header.
+func CSRFMiddleware(options ...func(*csrf)) gin.HandlerFunc {
+	x := &csrf{
+		ttl:              12 * time.Hour,
+		cookieName:       "csrf-token",
+		formField:        "csrf_token",
+		protectedCookies: []string{"refresh-token"},
+		errorResponse:    response{Error: InvalidCSRFTokenError.Error()},
+	}
+
+	for _, opt := range options {
+		opt(x)
+	}
+
+	return func(c *gin.Context) {
+		if !x.protects(c) {
+			c.Next()
+			return
+		}
+
+		if util.SliceContains([]string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}, c.Request.Method) {
+			if err := x.issue(c); err != nil {
+				middleware.LoggerFrom(c).Errorf("failed to issue CSRF token with error: %v", err)
+			}
+			c.Next()
+			return
+		}
+
+		if !x.checkOrigin(c) || !x.checkToken(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, x.errorResponse)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// WithCSRFSynchronizerToken switches to tokens signed with the secret for the authenticated subject, valid for ttl.
+func WithCSRFSynchronizerToken(secret []byte, ttl time.Duration) func(*csrf) {
+	return func(x *csrf) {
+		x.secret = secret
+		x.ttl = ttl
+	}
+}
+
+// WithCSRFCookieName sets the name of the double submit cookie. Defaults to "csrf-token".
+func WithCSRFCookieName(n string) func(*csrf) {
+	return func(x *csrf) {
+		x.cookieName = n
+	}
+}
+
+// WithCSRFFormField sets the form field the token may be submitted in. Defaults to "csrf_token".
+func WithCSRFFormField(f string) func(*csrf) {
+	return func(x *csrf) {
+		x.formField = f
+	}
+}
+
+// WithTrustedOrigins sets the origins besides the one of the request unsafe requests may come from,
+// e.g. "https://app.example.com".
+func WithTrustedOrigins(origins ...string) func(*csrf) {
+	return func(x *csrf) {
+		x.trustedOrigins = origins
+	}
+}
+
+// WithRequireOrigin sets whether unsafe requests without Origin and Referer headers are rejected. Defaults to false,
+// as some clients strip both, relying on the token alone.
+func WithRequireOrigin(r bool) func(*csrf) {
+	return func(x *csrf) {
+		x.requireOrigin = r
+	}
+}
+
+// WithCSRFProtectedCookies sets the cookies which subject the requests sending them to the checks, even if they are
+// not authenticated with a cookie. Defaults to "refresh-token", the refresh token cookie of TokenRotation, whose
+// handlers read it on their own; it must match the WithRefreshCookie name.
+func WithCSRFProtectedCookies(names ...string) func(*csrf) {
+	return func(x *csrf) {
+		x.protectedCookies = names
+	}
+}
+
+func WithCSRFErrorResponse(r any) func(*csrf) {
+	return func(x *csrf) {
+		x.errorResponse = r
+	}
+}
+
+// CSRFTokenFrom returns the CSRF token CSRFMiddleware issued for the request, to be embedded in forms.
+func CSRFTokenFrom(c *gin.Context) (string, bool) {
+	token := c.GetString(csrfTokenContextKey)
+	return token, token != ""
+}
+
+// protects reports whether the request is authenticated with a cookie or sends one of the protected cookies.
+func (x *csrf) protects(c *gin.Context) bool {
+	if source, ok := TokenSourceFrom(c); ok && source == SourceCookie {
+		return true
+	}
+
+	for _, name := range x.protectedCookies {
+		if v, err := c.Cookie(name); err == nil && v != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (x *csrf) issue(c *gin.Context) error {
+	var token string
+	if x.secret != nil {
+		t, err := x.sign(csrfSubject(c), time.Now())
+		if err != nil {
+			return err
+		}
+		token = t
+	} else {
+		token, _ = c.Cookie(x.cookieName)
+		if token == "" {
+			t, err := newCSRFNonce()
+			if err != nil {
+				return err
+			}
+			token = t
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     x.cookieName,
+				Value:    token,
+				Path:     "/",
+				Secure:   true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+
+	c.Set(csrfTokenContextKey, token)
+	c.Header(CSRFHeader, token)
+
+	return nil
+}
+
+func (x *csrf) checkToken(c *gin.Context) bool {
+	submitted := c.GetHeader(CSRFHeader)
+	if submitted == "" {
+		submitted = c.PostForm(x.formField)
+	}
+	if submitted == "" {
+		return false
+	}
+
+	if x.secret != nil {
+		return x.verify(csrfSubject(c), submitted, time.Now())
+	}
+
+	expected, _ := c.Cookie(x.cookieName)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(submitted), []byte(expected)) == 1
+}
+
+// checkOrigin reports whether the request comes from its own origin or a trusted one.
+func (x *csrf) checkOrigin(c *gin.Context) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" || origin == "null" {
+		if referer, err := url.Parse(c.GetHeader("Referer")); err == nil && referer.Host != "" {
+			origin = referer.Scheme + "://" + referer.Host
+		}
+	}
+	if origin == "" || origin == "null" {
+		return !x.requireOrigin
+	}
+
+	if util.SliceContains(x.trustedOrigins, origin) {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	host := c.Request.Host
+	if info, ok := middleware.ClientInfoFrom(c); ok && info.Host != "" {
+		host = info.Host
+	}
+
+	return strings.EqualFold(u.Host, host)
+}
+
+// sign returns a token of the form "<issued at>.<nonce>.<signature>" for the subject.
+func (x *csrf) sign(subject string, now time.Time) (string, error) {
+	nonce, err := newCSRFNonce()
+	if err != nil {
+		return "", err
+	}
+
+	payload := strconv.FormatInt(now.Unix(), 36) + "." + nonce
+	return payload + "." + x.mac(subject, payload), nil
+}
+
+func (x *csrf) verify(subject, token string, now time.Time) bool {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return false
+	}
+
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(x.mac(subject, payload))) {
+		return false
+	}
+
+	issuedAt, err := strconv.ParseInt(strings.SplitN(payload, ".", 2)[0], 36, 64)
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(time.Unix(issuedAt, 0)) <= x.ttl
+}
+
+func (x *csrf) mac(subject, payload string) string {
+	mac := hmac.New(sha256.New, x.secret)
+	mac.Write([]byte(subject + "\n" + payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// csrfSubject returns the subject synchronizer tokens are bound to.
+func csrfSubject(c *gin.Context) string {
+	claims, ok := mapClaimsFrom(c)
+	if !ok {
+		return ""
+	}
+
+	subject, _ := claims.GetSubject()
+	return subject
+}
+
+func newCSRFNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,240 @@
+package authentication
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCSRFToken = "csrf-token-value"
+
+func newCSRFRouter(source TokenSource, options ...func(*csrf)) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if source != "" {
+			c.Set(claimsContextKey, jwt.MapClaims{"sub": testUsername})
+			c.Set(tokenSourceContextKey, source)
+		}
+	}, CSRFMiddleware(options...))
+	handler := func(c *gin.Context) {
+		token, _ := CSRFTokenFrom(c)
+		c.String(http.StatusOK, "%s", token)
+	}
+	r.GET("/", handler)
+	r.POST("/", handler)
+
+	return r
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		source     TokenSource
+		options    []func(*csrf)
+		setup      func(req *http.Request)
+		wantStatus int
+	}{
+		{
+			name:   "header token skipped",
+			source: SourceHeader,
+			setup: func(req *http.Request) {
+				req.Header.Set("Origin", "https://evil.example")
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unauthenticated skipped",
+			setup:      func(req *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "refresh cookie protected",
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "refresh-token", Value: "refresh"})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "refresh cookie double submit",
+			setup: func(req *http.Request) {
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "refresh-token", Value: "refresh"})
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "protected cookies replaced",
+			options: []func(*csrf){WithCSRFProtectedCookies("session-refresh")},
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "session-refresh", Value: "refresh"})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "double submit",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.Header.Set("Origin", "https://example.com")
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "double submit form field",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.Body = io.NopCloser(strings.NewReader(url.Values{"csrf_token": {testCSRFToken}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "mismatched token",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: "other"})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "missing token",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "cross origin",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.Header.Set("Origin", "https://evil.example")
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "cross origin referer",
+			source: SourceCookie,
+			setup: func(req *http.Request) {
+				req.Header.Set("Referer", "https://evil.example/form")
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:    "trusted origin",
+			source:  SourceCookie,
+			options: []func(*csrf){WithTrustedOrigins("https://app.example")},
+			setup: func(req *http.Request) {
+				req.Header.Set("Origin", "https://app.example")
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "required origin missing",
+			source:  SourceCookie,
+			options: []func(*csrf){WithRequireOrigin(true)},
+			setup: func(req *http.Request) {
+				req.Header.Set(CSRFHeader, testCSRFToken)
+				req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCSRFRouter(tt.source, tt.options...)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "https://example.com/", nil)
+			tt.setup(req)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.JSONEq(t, `{"error":"invalid CSRF token"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareIssue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := newCSRFRouter(SourceCookie)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	token := w.Header().Get(CSRFHeader)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, token, w.Body.String())
+	cookie := responseCookie(w, "csrf-token")
+	if assert.NotNil(t, cookie) {
+		assert.Equal(t, token, cookie.Value)
+		assert.False(t, cookie.HttpOnly)
+	}
+
+	// The existing cookie is kept.
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+	r.ServeHTTP(w, req)
+	assert.Equal(t, testCSRFToken, w.Header().Get(CSRFHeader))
+	assert.Nil(t, responseCookie(w, "csrf-token"))
+}
+
+func TestCSRFSynchronizerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	secret := []byte(testString)
+	r := newCSRFRouter(SourceCookie, WithCSRFSynchronizerToken(secret, time.Hour))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	token := w.Header().Get(CSRFHeader)
+	assert.NotEmpty(t, token)
+	assert.Empty(t, w.Result().Cookies())
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(CSRFHeader, token)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(CSRFHeader, strings.Replace(token, ".", "x.", 1))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	x := &csrf{secret: secret, ttl: time.Hour}
+	now := time.Now()
+	signed, err := x.sign(testUsername, now)
+	assert.NoError(t, err)
+
+	assert.True(t, x.verify(testUsername, signed, now))
+	assert.False(t, x.verify("someone", signed, now))
+	assert.False(t, x.verify(testUsername, signed, now.Add(2*time.Hour)))
+}
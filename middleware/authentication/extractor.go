@@ -0,0 +1,48 @@
+package authentication
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// FromHeader extracts the access token of a header. With schemes, the header is parsed as an Authorization header
+// with one of them, see WithAuthorizationSchemes. Without, the header value is the token.
+func FromHeader(name string, schemes ...string) TokenExtractor {
+	return func(c *gin.Context) (string, TokenSource) {
+		h := c.GetHeader(name)
+		if len(schemes) > 0 {
+			return parseAuthorizationHeader(h, schemes), SourceHeader
+		}
+		if t := strings.TrimSpace(h); isToken68(t) {
+			return t, SourceHeader
+		}
+		return "", SourceHeader
+	}
+}
+
+func FromCookie(name string) TokenExtractor {
+	return func(c *gin.Context) (string, TokenSource) {
+		t, _ := c.Cookie(name)
+		return t, SourceCookie
+	}
+}
+
+// FromQuery extracts the access token of a query parameter. Note that URLs, and so the token, commonly end up in logs.
+func FromQuery(name string) TokenExtractor {
+	return func(c *gin.Context) (string, TokenSource) {
+		if t := c.Query(name); isToken68(t) {
+			return t, SourceQuery
+		}
+		return "", SourceQuery
+	}
+}
+
+// FromForm extracts the access token of a form field of the request body.
+func FromForm(name string) TokenExtractor {
+	return func(c *gin.Context) (string, TokenSource) {
+		if t := c.PostForm(name); isToken68(t) {
+			return t, SourceForm
+		}
+		return "", SourceForm
+	}
+}
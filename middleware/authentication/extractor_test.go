@@ -0,0 +1,108 @@
+package authentication
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenExtractors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		extractor  TokenExtractor
+		setup      func(req *http.Request)
+		want       string
+		wantSource TokenSource
+	}{
+		{
+			name:      "header with scheme",
+			extractor: FromHeader("Authorization", "Bearer"),
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", "bearer "+testJWTString)
+			},
+			want:       testJWTString,
+			wantSource: SourceHeader,
+		},
+		{
+			name:      "header with other scheme",
+			extractor: FromHeader("Authorization", "Bearer"),
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", "Basic "+testJWTString)
+			},
+			wantSource: SourceHeader,
+		},
+		{
+			name:      "header without scheme",
+			extractor: FromHeader("X-Access-Token"),
+			setup: func(req *http.Request) {
+				req.Header.Set("X-Access-Token", " "+testJWTString)
+			},
+			want:       testJWTString,
+			wantSource: SourceHeader,
+		},
+		{
+			name:      "cookie",
+			extractor: FromCookie(cookieStr),
+			setup: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: cookieStr, Value: testJWTString})
+			},
+			want:       testJWTString,
+			wantSource: SourceCookie,
+		},
+		{
+			name:      "query",
+			extractor: FromQuery("access_token"),
+			setup: func(req *http.Request) {
+				req.URL.RawQuery = "access_token=" + testJWTString
+			},
+			want:       testJWTString,
+			wantSource: SourceQuery,
+		},
+		{
+			name:      "invalid query",
+			extractor: FromQuery("access_token"),
+			setup: func(req *http.Request) {
+				req.URL.RawQuery = "access_token=" + url.QueryEscape(testString)
+			},
+			wantSource: SourceQuery,
+		},
+		{
+			name:      "form",
+			extractor: FromForm("access_token"),
+			setup: func(req *http.Request) {
+				req.Method = http.MethodPost
+				req.Body = io.NopCloser(strings.NewReader("access_token=" + testJWTString))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			},
+			want:       testJWTString,
+			wantSource: SourceForm,
+		},
+		{
+			name:       "missing",
+			extractor:  FromCookie(cookieStr),
+			setup:      func(req *http.Request) {},
+			wantSource: SourceCookie,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.setup(c.Request)
+
+			token, source := tt.extractor(c)
+
+			assert.Equal(t, tt.want, token)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
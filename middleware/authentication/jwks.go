@@ -0,0 +1,362 @@
+package authentication
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Novometrix/util/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// JWKSFetcher returns a JSON Web Key Set document.
+type JWKSFetcher func(ctx context.Context) ([]byte, error)
+
+// JWKSFromURL fetches the key set from url, with http.DefaultClient if client is nil.
+func JWKSFromURL(url string, client *http.Client) JWKSFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch JWKS %s: %s", url, resp.Status)
+		}
+
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+}
+
+// JWKSFromFile reads the key set from the file at path.
+func JWKSFromFile(path string) JWKSFetcher {
+	return func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwksKey struct {
+	key any
+	// alg, if set, is the only algorithm the key may verify.
+	alg string
+}
+
+type jwksVerifier struct {
+	fetch              JWKSFetcher
+	parserOptions      []jwt.ParserOption
+	minRefreshInterval time.Duration
+	fetchTimeout       time.Duration
+	now                func() time.Time
+
+	mu          sync.RWMutex
+	keys        map[string]jwksKey
+	lastRefresh time.Time
+	refreshMu   sync.Mutex
+}
+
+type JWKSVerifier interface {
+	Verifier
+	// Refresh fetches the key set again, replacing the keys.
+	Refresh(ctx context.Context) error
+	// RefreshEvery refreshes the key set at the interval until ctx is done, logging failures.
+	RefreshEvery(ctx context.Context, interval time.Duration)
+}
+
+// NewJWKSVerifier returns a Verifier validating tokens signed with the RSA, ECDSA or Ed25519 keys of a JSON Web Key
+// Set, looked up by the "kid" header of the tokens. The key set is fetched once before returning, and again when a
+// token has an unknown "kid", at most once a minute, see WithJWKSMinRefreshInterval. Fetches time out after 10 seconds,
+// see WithJWKSFetchTimeout. Keys failing to parse are skipped.
+func NewJWKSVerifier(ctx context.Context, fetch JWKSFetcher, options ...func(*jwksVerifier)) (JWKSVerifier, error) {
+	v := &jwksVerifier{
+		fetch:              fetch,
+		minRefreshInterval: time.Minute,
+		fetchTimeout:       10 * time.Second,
+		now:                time.Now,
+		keys:               map[string]jwksKey{},
+	}
+
+	for _, opt := range options {
+		opt(v)
+	}
+
+	// Symmetric algorithms are excluded, the keys being public.
+	v.parserOptions = append([]jwt.ParserOption{jwt.WithValidMethods([]string{
+		"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+	})}, v.parserOptions...)
+
+	if err := v.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// WithJWKSIssuer requires the "iss" claim of the tokens to be iss.
+func WithJWKSIssuer(iss string) func(*jwksVerifier) {
+	return func(v *jwksVerifier) {
+		v.parserOptions = append(v.parserOptions, jwt.WithIssuer(iss))
+	}
+}
+
+// WithJWKSAudience requires the "aud" claim of the tokens to contain aud.
+func WithJWKSAudience(aud string) func(*jwksVerifier) {
+	return func(v *jwksVerifier) {
+		v.parserOptions = append(v.parserOptions, jwt.WithAudience(aud))
+	}
+}
+
+// WithJWKSLeeway sets the clock skew tolerated when validating the time claims of the tokens.
+func WithJWKSLeeway(d time.Duration) func(*jwksVerifier) {
+	return func(v *jwksVerifier) {
+		v.parserOptions = append(v.parserOptions, jwt.WithLeeway(d))
+	}
+}
+
+// WithJWKSMinRefreshInterval sets the minimum interval between the refreshes caused by unknown "kid"s.
+func WithJWKSMinRefreshInterval(d time.Duration) func(*jwksVerifier) {
+	return func(v *jwksVerifier) {
+		v.minRefreshInterval = d
+	}
+}
+
+// WithJWKSFetchTimeout sets the time after which fetching the key set is given up. Defaults to 10 seconds.
+func WithJWKSFetchTimeout(d time.Duration) func(*jwksVerifier) {
+	return func(v *jwksVerifier) {
+		v.fetchTimeout = d
+	}
+}
+
+func (v *jwksVerifier) ValidateAccessTokenWithClaims(token string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(token, claims, v.keyFunc, v.parserOptions...)
+	return err
+}
+
+func (v *jwksVerifier) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k, ok := v.key(kid)
+	if !ok {
+		if err := v.refreshUnknownKey(); err != nil {
+			return nil, err
+		}
+		k, ok = v.key(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if k.alg != "" && k.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not verify %s", kid, token.Method.Alg())
+	}
+
+	return k.key, nil
+}
+
+func (v *jwksVerifier) key(kid string) (jwksKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	k, ok := v.keys[kid]
+	return k, ok
+}
+
+// refreshUnknownKey refreshes the key set for a token with an unknown "kid", unless it was refreshed less than the
+// minimum interval ago. The interval is checked once the refresh lock is held, so concurrent requests for the same
+// unknown key wait for a single fetch.
+func (v *jwksVerifier) refreshUnknownKey() error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	if !v.mayRefresh() {
+		return nil
+	}
+
+	return v.refresh(context.Background())
+}
+
+func (v *jwksVerifier) mayRefresh() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.now().Sub(v.lastRefresh) >= v.minRefreshInterval
+}
+
+func (v *jwksVerifier) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	return v.refresh(ctx)
+}
+
+// refresh fetches the key set, refreshMu being held.
+func (v *jwksVerifier) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, v.fetchTimeout)
+	defer cancel()
+
+	b, err := v.fetch(ctx)
+	if err == nil {
+		var keys map[string]jwksKey
+		if keys, err = parseJWKS(ctx, b); err == nil {
+			v.mu.Lock()
+			v.keys = keys
+			v.lastRefresh = v.now()
+			v.mu.Unlock()
+			return nil
+		}
+	}
+
+	// Failed refreshes count as well, so an unavailable key set is not fetched for every token.
+	v.mu.Lock()
+	v.lastRefresh = v.now()
+	v.mu.Unlock()
+
+	return err
+}
+
+func (v *jwksVerifier) RefreshEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := v.Refresh(ctx); err != nil {
+				middleware.LoggerFrom(ctx).Errorf("failed to refresh JWKS with error: %v", err)
+			}
+		}
+	}
+}
+
+// parseJWKS returns the signature keys of a JSON Web Key Set by "kid". Keys of unsupported types are skipped, as are
+// malformed keys, which are logged, so that one bad key does not disable the others.
+func parseJWKS(ctx context.Context, b []byte) (map[string]jwksKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			middleware.LoggerFrom(ctx).Warnf("skipping JWK %q failing to parse with error: %v", k.Kid, err)
+			continue
+		}
+		if key != nil {
+			keys[k.Kid] = jwksKey{key: key, alg: k.Alg}
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKey returns the public key of k, or nil if its type is not supported.
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,378 @@
+package authentication
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testJWKSKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+func newTestJWKSKeys(t *testing.T) []testJWKSKey {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	return []testJWKSKey{
+		{kid: "rsa", method: jwt.SigningMethodRS256, key: rsaKey},
+		{kid: "ec", method: jwt.SigningMethodES256, key: ecKey},
+		{kid: "ed", method: jwt.SigningMethodEdDSA, key: edKey},
+	}
+}
+
+func testJWKS(t *testing.T, keys ...testJWKSKey) []byte {
+	enc := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for _, k := range keys {
+		switch pub := k.key.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: k.kid, Alg: k.method.Alg(), N: enc(pub.N.Bytes()), E: enc(big.NewInt(int64(pub.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: k.kid, Crv: "P-256", X: enc(pub.X.Bytes()), Y: enc(pub.Y.Bytes())})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, jwk{Kty: "OKP", Kid: k.kid, Crv: "Ed25519", X: enc(pub)})
+		}
+	}
+	// Encryption keys are ignored.
+	set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: "enc", Use: "enc"})
+
+	b, err := json.Marshal(set)
+	assert.NoError(t, err)
+
+	return b
+}
+
+func signTestToken(t *testing.T, k testJWKSKey, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+
+	s, err := token.SignedString(k.key)
+	assert.NoError(t, err)
+
+	return s
+}
+
+type testJWKSServer struct {
+	mu       sync.Mutex
+	jwks     []byte
+	requests int
+	// delay is waited before answering, or until the request is cancelled.
+	delay time.Duration
+}
+
+func (s *testJWKSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	jwks, delay := s.jwks, s.delay
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+	_, _ = w.Write(jwks)
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	ctx := context.Background()
+	keys := newTestJWKSKeys(t)
+
+	jwksServer := &testJWKSServer{jwks: testJWKS(t, keys...)}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	v, err := NewJWKSVerifier(ctx, JWKSFromURL(server.URL, nil), WithJWKSIssuer("https://issuer.example"), WithJWKSAudience("api"))
+	assert.NoError(t, err)
+
+	valid := jwt.RegisteredClaims{
+		Issuer:    "https://issuer.example",
+		Audience:  jwt.ClaimStrings{"api"},
+		Subject:   testUsername,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	for _, k := range keys {
+		t.Run(k.kid, func(t *testing.T) {
+			claims := jwt.MapClaims{}
+			assert.NoError(t, v.ValidateAccessTokenWithClaims(signTestToken(t, k, valid), &claims))
+			assert.Equal(t, testUsername, claims["sub"])
+		})
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr error
+	}{
+		{
+			name: "expired",
+			token: func() string {
+				claims := valid
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				return signTestToken(t, keys[0], claims)
+			},
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := valid
+				claims.Issuer = "https://other.example"
+				return signTestToken(t, keys[0], claims)
+			},
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := valid
+				claims.Audience = jwt.ClaimStrings{"other"}
+				return signTestToken(t, keys[0], claims)
+			},
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "algorithm of other key",
+			token: func() string {
+				k := keys[0]
+				k.method = jwt.SigningMethodRS512
+				return signTestToken(t, k, valid)
+			},
+			wantErr: jwt.ErrTokenUnverifiable,
+		},
+		{
+			name: "symmetric algorithm",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, valid)
+				token.Header["kid"] = "rsa"
+				s, _ := token.SignedString([]byte(testString))
+				return s
+			},
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateAccessTokenWithClaims(tt.token(), &jwt.MapClaims{})
+			assert.True(t, errors.Is(err, tt.wantErr), "%v", err)
+		})
+	}
+}
+
+func TestJWKSVerifierRefresh(t *testing.T) {
+	ctx := context.Background()
+	keys := newTestJWKSKeys(t)
+
+	jwksServer := &testJWKSServer{jwks: testJWKS(t, keys[0])}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	v, err := NewJWKSVerifier(ctx, JWKSFromURL(server.URL, server.Client()))
+	assert.NoError(t, err)
+	now := v.(*jwksVerifier).lastRefresh
+	v.(*jwksVerifier).now = func() time.Time {
+		return now
+	}
+
+	claims := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}
+	token := signTestToken(t, keys[1], claims)
+
+	// The key is published after the last refresh, which was too recent to fetch the key set again.
+	jwksServer.mu.Lock()
+	jwksServer.jwks = testJWKS(t, keys...)
+	jwksServer.mu.Unlock()
+
+	assert.Error(t, v.ValidateAccessTokenWithClaims(token, &jwt.MapClaims{}))
+	assert.Equal(t, 1, jwksServer.requests)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, v.ValidateAccessTokenWithClaims(token, &jwt.MapClaims{}))
+	assert.Equal(t, 2, jwksServer.requests)
+
+	// Known keys do not cause refreshes.
+	now = now.Add(time.Minute)
+	assert.NoError(t, v.ValidateAccessTokenWithClaims(token, &jwt.MapClaims{}))
+	assert.Equal(t, 2, jwksServer.requests)
+
+	refreshCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		v.RefreshEvery(refreshCtx, time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		jwksServer.mu.Lock()
+		defer jwksServer.mu.Unlock()
+		return jwksServer.requests > 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestJWKSVerifierConcurrentRefresh(t *testing.T) {
+	keys := newTestJWKSKeys(t)
+
+	jwksServer := &testJWKSServer{jwks: testJWKS(t, keys[0])}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	v, err := NewJWKSVerifier(context.Background(), JWKSFromURL(server.URL, nil))
+	assert.NoError(t, err)
+	now := v.(*jwksVerifier).lastRefresh.Add(time.Minute)
+	v.(*jwksVerifier).now = func() time.Time {
+		return now
+	}
+
+	jwksServer.mu.Lock()
+	jwksServer.jwks = testJWKS(t, keys...)
+	jwksServer.delay = 50 * time.Millisecond
+	jwksServer.mu.Unlock()
+
+	token := signTestToken(t, keys[1], jwt.RegisteredClaims{Subject: testUsername})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = v.ValidateAccessTokenWithClaims(token, &jwt.MapClaims{})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	// A single refresh serves all the requests waiting for the unknown key.
+	assert.Equal(t, 2, jwksServer.requests)
+}
+
+func TestJWKSVerifierFetchTimeout(t *testing.T) {
+	jwksServer := &testJWKSServer{delay: time.Minute}
+	server := httptest.NewServer(jwksServer)
+	defer server.Close()
+
+	start := time.Now()
+	_, err := NewJWKSVerifier(context.Background(), JWKSFromURL(server.URL, nil), WithJWKSFetchTimeout(50*time.Millisecond))
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestJWKSFromFile(t *testing.T) {
+	keys := newTestJWKSKeys(t)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, testJWKS(t, keys...), 0o600))
+
+	v, err := NewJWKSVerifier(context.Background(), JWKSFromFile(path))
+	assert.NoError(t, err)
+
+	token := signTestToken(t, keys[2], jwt.RegisteredClaims{Subject: testUsername})
+	assert.NoError(t, v.ValidateAccessTokenWithClaims(token, &jwt.MapClaims{}))
+
+	_, err = NewJWKSVerifier(context.Background(), JWKSFromFile(filepath.Join(t.TempDir(), "missing.json")))
+	assert.Error(t, err)
+}
+
+func TestParseJWKS(t *testing.T) {
+	ctx := context.Background()
+
+	keys, err := parseJWKS(ctx, []byte(`{"keys": [{"kty": "EC", "kid": "bad", "crv": "P-256", "x": "AQ", "y": "AQ"}, {"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "`+base64.RawURLEncoding.EncodeToString(make([]byte, 32))+`"}]}`))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Contains(t, keys, "ed")
+
+	keys, err = parseJWKS(ctx, []byte(`{"keys": [{"kty": "oct", "kid": "secret", "k": "AQ"}]}`))
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	_, err = parseJWKS(ctx, []byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestVerifierAuthenticationMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys := newTestJWKSKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, testJWKS(t, keys...), 0o600))
+
+	v, err := NewJWKSVerifier(context.Background(), JWKSFromFile(path))
+	assert.NoError(t, err)
+
+	mw := NewTypedVerifierAuthenticationMiddleware[testClaims](v)
+
+	r := gin.New()
+	r.Use(mw.RequireAuthenticatedMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		claims, _ := ClaimsFrom[testClaims](c)
+		c.String(http.StatusOK, "%s", claims.Subject)
+	})
+
+	tests := []struct {
+		name       string
+		claims     jwt.RegisteredClaims
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "valid",
+			claims:     jwt.RegisteredClaims{Subject: testUsername, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			wantStatus: http.StatusOK,
+			wantBody:   testUsername,
+		},
+		{
+			name:       "expired",
+			claims:     jwt.RegisteredClaims{Subject: testUsername, ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"access token expired"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, keys[1], tt.claims))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package authentication
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Novometrix/util/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+type Operator string
+
+const (
+	OperatorEquals    Operator = "eq"
+	OperatorNotEquals Operator = "ne"
+	// OperatorIn matches if the attribute equals one of the values of the list.
+	OperatorIn Operator = "in"
+	// OperatorContains matches if the list attribute, e.g. the roles of the subject, contains the value.
+	OperatorContains Operator = "contains"
+	OperatorExists   Operator = "exists"
+)
+
+// Attributes are the attributes a policy is evaluated against, with the claims of the access token under "subject",
+// the request under "request" and the attributes provided by the handler under "resource".
+// The request attributes are "method", "path", "route", "params" and "headers", the header names being canonical,
+// e.g. "request.headers.X-Tenant-Id".
+type Attributes map[string]any
+
+// Condition compares the attribute at a dot-separated path, e.g. "resource.owner", against Value or, if ValueFrom is
+// set, against the attribute at that path, e.g. "subject.sub". Values are compared by their string representation,
+// so a path param matches a numeric claim. Lists may also be given as space-delimited strings.
+// Conditions on missing attributes never match, except for OperatorExists.
+type Condition struct {
+	Attribute string   `json:"attribute" yaml:"attribute"`
+	Operator  Operator `json:"operator" yaml:"operator"`
+	Value     any      `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueFrom string   `json:"value_from,omitempty" yaml:"value_from,omitempty"`
+}
+
+// PolicyRule matches if all its conditions do.
+type PolicyRule struct {
+	Name       string      `json:"name" yaml:"name"`
+	Effect     Effect      `json:"effect" yaml:"effect"`
+	Conditions []Condition `json:"conditions" yaml:"conditions"`
+}
+
+// AttributePolicy allows a request if one of its allow rules matches and none of its deny rules does.
+//
+//	policies:
+//	  - name: edit-document
+//	    rules:
+//	      - name: owner
+//	        effect: allow
+//	        conditions:
+//	          - {attribute: resource.owner, operator: eq, value_from: subject.sub}
+//	          - {attribute: resource.tenant, operator: eq, value_from: subject.tenant}
+//	      - name: admin
+//	        effect: allow
+//	        conditions:
+//	          - {attribute: subject.roles, operator: contains, value: admin}
+type AttributePolicy struct {
+	Name  string       `json:"name" yaml:"name"`
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+type Decision struct {
+	Policy  string
+	Allowed bool
+	// Rule is the name of the rule the decision was made by, empty if no rule matched.
+	Rule   string
+	Reason string
+}
+
+type policyEngine struct {
+	policies    map[string]AttributePolicy
+	logDecision func(c *gin.Context, d Decision)
+}
+
+type PolicyEngine interface {
+	// Evaluate decides the named policy for attrs. Unknown policies are denied.
+	Evaluate(name string, attrs Attributes) Decision
+	// Authorize decides the named policy for the claims stored by the authentication middleware, the request and
+	// the resource attributes, and logs the decision. resource may be a map or a struct, which is converted through
+	// its JSON representation, or nil.
+	Authorize(c *gin.Context, name string, resource any) Decision
+	// Policy returns the named policy for Authorization.RequirePolicy. resource, if not nil, provides the resource
+	// attributes of the request.
+	Policy(name string, resource func(c *gin.Context) any) Policy
+}
+
+// NewPolicyEngine returns a PolicyEngine deciding the policies, which are validated first.
+func NewPolicyEngine(policies []AttributePolicy, options ...func(*policyEngine)) (PolicyEngine, error) {
+	e := &policyEngine{
+		policies:    make(map[string]AttributePolicy, len(policies)),
+		logDecision: logDecision,
+	}
+
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := e.policies[p.Name]; exists {
+			return nil, fmt.Errorf("%w: duplicate policy %q", InvalidPolicyError, p.Name)
+		}
+		e.policies[p.Name] = p
+	}
+
+	for _, opt := range options {
+		opt(e)
+	}
+
+	return e, nil
+}
+
+// WithDecisionLogger sets the function the decisions of Authorize are logged with. Defaults to logging allowed
+// decisions at debug and denied decisions at info level to the request-scoped logger, see middleware.LoggerFrom.
+func WithDecisionLogger(f func(c *gin.Context, d Decision)) func(*policyEngine) {
+	return func(e *policyEngine) {
+		e.logDecision = f
+	}
+}
+
+// LoadPolicies reads the policies of a YAML or, if the file has the .json extension, JSON file with a top-level
+// "policies" list.
+func LoadPolicies(path string) ([]AttributePolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Policies []AttributePolicy `json:"policies" yaml:"policies"`
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &file)
+	} else {
+		err = yaml.Unmarshal(b, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policies %s: %w", path, err)
+	}
+
+	return file.Policies, nil
+}
+
+func (e *policyEngine) Evaluate(name string, attrs Attributes) Decision {
+	p, exists := e.policies[name]
+	if !exists {
+		return Decision{Policy: name, Reason: "unknown policy"}
+	}
+
+	d := Decision{Policy: name, Reason: "no allow rule matched"}
+	for _, r := range p.Rules {
+		if !r.matches(attrs) {
+			continue
+		}
+		if r.Effect == EffectDeny {
+			return Decision{Policy: name, Rule: r.Name, Reason: "deny rule matched"}
+		}
+		if !d.Allowed {
+			d = Decision{Policy: name, Allowed: true, Rule: r.Name, Reason: "allow rule matched"}
+		}
+	}
+
+	return d
+}
+
+func (e *policyEngine) Authorize(c *gin.Context, name string, resource any) Decision {
+	claims, _ := mapClaimsFrom(c)
+	return e.authorize(c, name, claims, resource)
+}
+
+func (e *policyEngine) Policy(name string, resource func(c *gin.Context) any) Policy {
+	return func(c *gin.Context, claims jwt.MapClaims) bool {
+		var r any
+		if resource != nil {
+			r = resource(c)
+		}
+		return e.authorize(c, name, claims, r).Allowed
+	}
+}
+
+func (e *policyEngine) authorize(c *gin.Context, name string, claims jwt.MapClaims, resource any) Decision {
+	attrs := Attributes{
+		"subject":  map[string]any(claims),
+		"request":  requestAttributes(c),
+		"resource": resource,
+	}
+	if resource != nil {
+		if _, ok := resource.(map[string]any); !ok {
+			m, err := toMap(resource)
+			if err != nil {
+				middleware.LoggerFrom(c).Errorf("failed to convert resource attributes with error: %v", err)
+			}
+			attrs["resource"] = m
+		}
+	}
+
+	d := e.Evaluate(name, attrs)
+	e.logDecision(c, d)
+
+	return d
+}
+
+func logDecision(c *gin.Context, d Decision) {
+	level := log.InfoLevel
+	if d.Allowed {
+		level = log.DebugLevel
+	}
+
+	middleware.LoggerFrom(c).WithFields(log.Fields{
+		"policy":  d.Policy,
+		"allowed": d.Allowed,
+		"rule":    d.Rule,
+		"reason":  d.Reason,
+	}).Log(level, "authorization decision")
+}
+
+func requestAttributes(c *gin.Context) map[string]any {
+	params := make(map[string]any, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = p.Value
+	}
+
+	attrs := map[string]any{
+		"route":  c.FullPath(),
+		"params": params,
+	}
+	if c.Request != nil {
+		headers := make(map[string]any, len(c.Request.Header))
+		for k, v := range c.Request.Header {
+			if len(v) > 0 {
+				headers[k] = v[0]
+			}
+		}
+		attrs["method"] = c.Request.Method
+		attrs["path"] = c.Request.URL.Path
+		attrs["headers"] = headers
+	}
+
+	return attrs
+}
+
+func (p AttributePolicy) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: missing name", InvalidPolicyError)
+	}
+
+	for _, r := range p.Rules {
+		if r.Effect != EffectAllow && r.Effect != EffectDeny {
+			return fmt.Errorf("%w: policy %q rule %q has unknown effect %q", InvalidPolicyError, p.Name, r.Name, r.Effect)
+		}
+		for _, cond := range r.Conditions {
+			switch cond.Operator {
+			case OperatorEquals, OperatorNotEquals, OperatorIn, OperatorContains, OperatorExists:
+			default:
+				return fmt.Errorf("%w: policy %q rule %q has unknown operator %q", InvalidPolicyError, p.Name, r.Name, cond.Operator)
+			}
+			if cond.Attribute == "" {
+				return fmt.Errorf("%w: policy %q rule %q has a condition without attribute", InvalidPolicyError, p.Name, r.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r PolicyRule) matches(attrs Attributes) bool {
+	for _, cond := range r.Conditions {
+		if !cond.matches(attrs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cond Condition) matches(attrs Attributes) bool {
+	v, ok := attrs.lookup(cond.Attribute)
+	if cond.Operator == OperatorExists {
+		return ok
+	}
+	if !ok {
+		return false
+	}
+
+	want := cond.Value
+	if cond.ValueFrom != "" {
+		if want, ok = attrs.lookup(cond.ValueFrom); !ok {
+			return false
+		}
+	}
+
+	switch cond.Operator {
+	case OperatorEquals:
+		return equalAttributes(v, want)
+	case OperatorNotEquals:
+		return !equalAttributes(v, want)
+	case OperatorIn:
+		return listContains(want, v)
+	case OperatorContains:
+		return listContains(v, want)
+	default:
+		return false
+	}
+}
+
+// lookup returns the attribute at the dot-separated path.
+func (attrs Attributes) lookup(path string) (any, bool) {
+	var v any = map[string]any(attrs)
+	for _, key := range strings.Split(path, ".") {
+		var ok bool
+		switch m := v.(type) {
+		case map[string]any:
+			v, ok = m[key]
+		case Attributes:
+			v, ok = m[key]
+		case jwt.MapClaims:
+			v, ok = m[key]
+		case map[string]string:
+			v, ok = m[key]
+		}
+		if !ok || v == nil {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+func equalAttributes(a, b any) bool {
+	return attributeString(a) == attributeString(b)
+}
+
+// attributeString returns the string representation attributes are compared by. Floats are formatted without
+// exponent, numeric claims being decoded as float64, so that e.g. 12345678 matches the path param "12345678".
+func attributeString(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func listContains(list, v any) bool {
+	if s, ok := list.(string); ok {
+		for _, e := range strings.Fields(s) {
+			if equalAttributes(e, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if equalAttributes(rv.Index(i).Interface(), v) {
+			return true
+		}
+	}
+
+	return false
+}
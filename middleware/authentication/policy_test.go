@@ -0,0 +1,347 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicies = []AttributePolicy{
+	{
+		Name: "edit-document",
+		Rules: []PolicyRule{
+			{
+				Name:   "owner",
+				Effect: EffectAllow,
+				Conditions: []Condition{
+					{Attribute: "resource.owner", Operator: OperatorEquals, ValueFrom: "subject.sub"},
+					{Attribute: "resource.tenant", Operator: OperatorEquals, ValueFrom: "subject.tenant"},
+				},
+			},
+			{
+				Name:   "admin",
+				Effect: EffectAllow,
+				Conditions: []Condition{
+					{Attribute: "subject.roles", Operator: OperatorContains, Value: "admin"},
+				},
+			},
+			{
+				Name:   "archived",
+				Effect: EffectDeny,
+				Conditions: []Condition{
+					{Attribute: "resource.archived", Operator: OperatorEquals, Value: true},
+				},
+			},
+		},
+	},
+	{
+		Name: "read-tenant",
+		Rules: []PolicyRule{
+			{
+				Name:   "same-tenant",
+				Effect: EffectAllow,
+				Conditions: []Condition{
+					{Attribute: "request.method", Operator: OperatorIn, Value: []string{http.MethodGet, http.MethodHead}},
+					{Attribute: "request.params.tenant", Operator: OperatorEquals, ValueFrom: "subject.tenant"},
+					{Attribute: "request.headers.X-Client", Operator: OperatorExists},
+				},
+			},
+		},
+	},
+}
+
+func TestPolicyEngineEvaluate(t *testing.T) {
+	e, err := NewPolicyEngine(testPolicies)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		policy   string
+		attrs    Attributes
+		wantRule string
+		allowed  bool
+	}{
+		{
+			name:   "owner",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  map[string]any{"sub": "42", "tenant": "acme"},
+				"resource": map[string]any{"owner": 42, "tenant": "acme"},
+			},
+			wantRule: "owner",
+			allowed:  true,
+		},
+		{
+			name:   "owner with numeric claim",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  jwt.MapClaims{"sub": float64(12345678), "tenant": "acme"},
+				"resource": map[string]any{"owner": "12345678", "tenant": "acme"},
+			},
+			wantRule: "owner",
+			allowed:  true,
+		},
+		{
+			name:   "other owner with numeric claim",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  jwt.MapClaims{"sub": float64(12345678), "tenant": "acme"},
+				"resource": map[string]any{"owner": "12345679", "tenant": "acme"},
+			},
+		},
+		{
+			name:   "owner of another tenant",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  map[string]any{"sub": "42", "tenant": "acme"},
+				"resource": map[string]any{"owner": 42, "tenant": "other"},
+			},
+		},
+		{
+			name:   "admin",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  map[string]any{"sub": "1", "roles": []any{"admin"}},
+				"resource": map[string]any{"owner": 42},
+			},
+			wantRule: "admin",
+			allowed:  true,
+		},
+		{
+			name:   "deny overrides allow",
+			policy: "edit-document",
+			attrs: Attributes{
+				"subject":  map[string]any{"roles": "admin"},
+				"resource": map[string]any{"archived": true},
+			},
+			wantRule: "archived",
+		},
+		{
+			name:   "missing attributes",
+			policy: "edit-document",
+			attrs:  Attributes{},
+		},
+		{
+			name:   "unknown policy",
+			policy: "delete-document",
+			attrs:  Attributes{"subject": map[string]any{"roles": "admin"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := e.Evaluate(tt.policy, tt.attrs)
+
+			assert.Equal(t, tt.allowed, d.Allowed)
+			assert.Equal(t, tt.wantRule, d.Rule)
+			assert.Equal(t, tt.policy, d.Policy)
+		})
+	}
+}
+
+func TestNewPolicyEngine(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []AttributePolicy
+	}{
+		{
+			name:     "missing name",
+			policies: []AttributePolicy{{}},
+		},
+		{
+			name:     "duplicate",
+			policies: []AttributePolicy{{Name: testString}, {Name: testString}},
+		},
+		{
+			name:     "unknown effect",
+			policies: []AttributePolicy{{Name: testString, Rules: []PolicyRule{{Effect: "maybe"}}}},
+		},
+		{
+			name: "unknown operator",
+			policies: []AttributePolicy{{Name: testString, Rules: []PolicyRule{{
+				Effect:     EffectAllow,
+				Conditions: []Condition{{Attribute: "subject.sub", Operator: "like"}},
+			}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicyEngine(tt.policies)
+			assert.True(t, errors.Is(err, InvalidPolicyError))
+		})
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"policies.yaml": `
+policies:
+  - name: edit-document
+    rules:
+      - name: owner
+        effect: allow
+        conditions:
+          - {attribute: resource.owner, operator: eq, value_from: subject.sub}
+`,
+		"policies.json": `{"policies": [{"name": "edit-document", "rules": [{"name": "owner", "effect": "allow",
+	"conditions": [{"attribute": "resource.owner", "operator": "eq", "value_from": "subject.sub"}]}]}]}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			policies, err := LoadPolicies(path)
+			assert.NoError(t, err)
+
+			e, err := NewPolicyEngine(policies)
+			assert.NoError(t, err)
+
+			AssertDecision(t, e, "edit-document", Attributes{
+				"subject":  map[string]any{"sub": testUsername},
+				"resource": map[string]any{"owner": testUsername},
+			}, true)
+		})
+	}
+
+	_, err := LoadPolicies(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestPolicyEnginePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type document struct {
+		Owner  string `json:"owner"`
+		Tenant string `json:"tenant"`
+	}
+
+	var decisions []Decision
+	e, err := NewPolicyEngine(testPolicies, WithDecisionLogger(func(c *gin.Context, d Decision) {
+		decisions = append(decisions, d)
+	}))
+	assert.NoError(t, err)
+
+	a := NewAuthorizationMiddleware()
+	claims := jwt.MapClaims{"sub": testUsername, "tenant": "acme"}
+
+	tests := []struct {
+		name       string
+		path       string
+		header     bool
+		policy     gin.HandlerFunc
+		wantStatus int
+	}{
+		{
+			name: "resource owner",
+			path: "/documents/" + testUsername,
+			policy: a.RequirePolicy(e.Policy("edit-document", func(c *gin.Context) any {
+				return document{Owner: c.Param("id"), Tenant: "acme"}
+			})),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "not the resource owner",
+			path: "/documents/someone",
+			policy: a.RequirePolicy(e.Policy("edit-document", func(c *gin.Context) any {
+				return document{Owner: c.Param("id"), Tenant: "acme"}
+			})),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "request attributes",
+			path:       "/tenants/acme",
+			header:     true,
+			policy:     a.RequirePolicy(e.Policy("read-tenant", nil)),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing header",
+			path:       "/tenants/acme",
+			policy:     a.RequirePolicy(e.Policy("read-tenant", nil)),
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions = nil
+
+			r := gin.New()
+			setClaims := func(c *gin.Context) {
+				c.Set(claimsContextKey, claims)
+			}
+			ok := func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			}
+			r.GET("/documents/:id", setClaims, tt.policy, ok)
+			r.GET("/tenants/:tenant", setClaims, tt.policy, ok)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header {
+				req.Header.Set("X-Client", testString)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if assert.Len(t, decisions, 1) {
+				assert.Equal(t, tt.wantStatus == http.StatusOK, decisions[0].Allowed)
+			}
+		})
+	}
+}
+
+func TestPolicyEngineAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	e, err := NewPolicyEngine(testPolicies)
+	assert.NoError(t, err)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/documents/1", nil)
+	c.Set(claimsContextKey, testClaims{Roles: []string{"admin"}})
+
+	d := e.Authorize(c, "edit-document", map[string]any{"owner": "1"})
+	assert.True(t, d.Allowed)
+	assert.Equal(t, "admin", d.Rule)
+
+	d = e.Authorize(c, "edit-document", map[string]any{"archived": true})
+	assert.False(t, d.Allowed)
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertDecision(t *testing.T) {
+	e, err := NewPolicyEngine(testPolicies)
+	assert.NoError(t, err)
+
+	rt := &recordingT{}
+	attrs := Attributes{"subject": map[string]any{"roles": []string{"admin"}}}
+
+	assert.True(t, AssertDecision(rt, e, "edit-document", attrs, true))
+	assert.Empty(t, rt.errors)
+
+	assert.False(t, AssertDecision(rt, e, "edit-document", attrs, false))
+	assert.Len(t, rt.errors, 1)
+}
@@ -0,0 +1,313 @@
+package authentication
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/Novometrix/util/middleware"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// AccessTokenIssuer issues signed access tokens, e.g. with the claims of the subject loaded from the user store.
+type AccessTokenIssuer interface {
+	IssueAccessToken(ctx context.Context, subject string) (token string, expiresAt time.Time, err error)
+}
+
+// AccessTokenIssuerFunc adapts a function to an AccessTokenIssuer.
+type AccessTokenIssuerFunc func(ctx context.Context, subject string) (string, time.Time, error)
+
+func (f AccessTokenIssuerFunc) IssueAccessToken(ctx context.Context, subject string) (string, time.Time, error) {
+	return f(ctx, subject)
+}
+
+// TokenPair is the response body of the TokenRotation handlers. The refresh token is only included if the tokens
+// are not set as cookies.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token"`
+}
+
+type tokenRotation struct {
+	issuer AccessTokenIssuer
+	store  RefreshTokenStore
+	now    func() time.Time
+
+	refreshTokenTTL    time.Duration
+	setCookies         bool
+	cookieName         string
+	authentication     Authentication
+	refreshCookieName  string
+	refreshCookiePath  string
+	errorResponse      any
+	tokenReuseResponse any
+}
+
+type TokenRotation interface {
+	// Issue issues a token pair for the subject and writes it, for login handlers to call once the subject is
+	// authenticated. It starts a new refresh token family.
+	Issue(c *gin.Context, subject string)
+	// RefreshHandler rotates the refresh token of the request, read from the refresh cookie or the "refresh_token"
+	// field of the body, and writes a new token pair. Reusing a rotated refresh token revokes its whole family.
+	RefreshHandler() gin.HandlerFunc
+	// RevokeHandler revokes the family of the refresh token of the request and clears the cookies, e.g. on logout.
+	RevokeHandler() gin.HandlerFunc
+}
+
+// NewTokenRotation returns a TokenRotation issuing access tokens with issuer and keeping refresh tokens in store.
+// The access token cookie is named "access-token" by default, as in NewAuthenticationMiddleware, see
+// WithRotationCookieName.
+func NewTokenRotation(issuer AccessTokenIssuer, store RefreshTokenStore, options ...func(*tokenRotation)) TokenRotation {
+	r := &tokenRotation{
+		issuer:             issuer,
+		store:              store,
+		now:                time.Now,
+		refreshTokenTTL:    30 * 24 * time.Hour,
+		setCookies:         true,
+		cookieName:         "access-token",
+		refreshCookieName:  "refresh-token",
+		refreshCookiePath:  "/",
+		errorResponse:      response{Error: http.StatusText(http.StatusUnauthorized)},
+		tokenReuseResponse: response{Error: RefreshTokenReusedError.Error()},
+	}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
+}
+
+func WithRefreshTokenTTL(ttl time.Duration) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.refreshTokenTTL = ttl
+	}
+}
+
+// WithRotationCookies sets whether the tokens are set as cookies. Defaults to true.
+func WithRotationCookies(set bool) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.setCookies = set
+	}
+}
+
+// WithRotationCookieName sets the name of the access token cookie, which must match the WithCookieName of the
+// authentication middleware.
+func WithRotationCookieName(n string) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.cookieName = n
+	}
+}
+
+// WithRotationAuthentication sets the access token cookie with the cookie name and options of the authentication
+// middleware, see Authentication.SetAccessTokenCookie, instead of WithRotationCookieName.
+func WithRotationAuthentication(a Authentication) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.authentication = a
+	}
+}
+
+// WithRefreshCookie sets the name and path of the refresh token cookie, e.g. to only send it to the refresh endpoint.
+// Defaults to "refresh-token" and "/". A different name must be given to WithCSRFProtectedCookies for CSRFMiddleware
+// to protect the handlers.
+func WithRefreshCookie(name, path string) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.refreshCookieName = name
+		r.refreshCookiePath = path
+	}
+}
+
+func WithRotationErrorResponse(resp any) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.errorResponse = resp
+	}
+}
+
+func WithTokenReuseResponse(resp any) func(*tokenRotation) {
+	return func(r *tokenRotation) {
+		r.tokenReuseResponse = resp
+	}
+}
+
+func (r *tokenRotation) Issue(c *gin.Context, subject string) {
+	familyID, err := util.NewUUIDv7()
+	if err != nil {
+		middleware.LoggerFrom(c).Errorf("failed to generate refresh token family with error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	r.issue(c, subject, familyID)
+}
+
+func (r *tokenRotation) RefreshHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := r.use(c)
+		if err != nil {
+			r.fail(c, err)
+			return
+		}
+
+		if t.Used {
+			if err = r.store.RevokeFamily(c, t.FamilyID); err != nil {
+				middleware.LoggerFrom(c).Errorf("failed to revoke refresh token family with error: %v", err)
+			}
+			middleware.LoggerFrom(c).WithField("subject", t.Subject).Warn("refresh token reused, revoked its family")
+			r.fail(c, RefreshTokenReusedError)
+			return
+		}
+
+		if !t.ExpiresAt.After(r.now()) {
+			r.fail(c, RefreshTokenExpiredError)
+			return
+		}
+
+		r.issue(c, t.Subject, t.FamilyID)
+	}
+}
+
+func (r *tokenRotation) RevokeHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := r.use(c)
+		if err == nil {
+			err = r.store.RevokeFamily(c, t.FamilyID)
+		}
+		if err != nil && !errors.Is(err, RefreshTokenNotFoundError) {
+			middleware.LoggerFrom(c).Errorf("failed to revoke refresh token family with error: %v", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		r.clearCookies(c)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// use marks the refresh token of the request as used and returns its record.
+func (r *tokenRotation) use(c *gin.Context) (RefreshToken, error) {
+	token, _ := c.Cookie(r.refreshCookieName)
+	if token == "" {
+		var req refreshRequest
+		if err := c.ShouldBind(&req); err == nil {
+			token = req.RefreshToken
+		}
+	}
+	if token == "" {
+		return RefreshToken{}, RefreshTokenNotFoundError
+	}
+
+	return r.store.Use(c, hashToken(token))
+}
+
+func (r *tokenRotation) issue(c *gin.Context, subject, familyID string) {
+	accessToken, expiresAt, err := r.issuer.IssueAccessToken(c, subject)
+	if err != nil {
+		middleware.LoggerFrom(c).Errorf("failed to issue access token with error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		middleware.LoggerFrom(c).Errorf("failed to generate refresh token with error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	now := r.now()
+	t := RefreshToken{
+		ID:        hashToken(refreshToken),
+		FamilyID:  familyID,
+		Subject:   subject,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(r.refreshTokenTTL),
+	}
+	if err = r.store.Save(c, t); err != nil {
+		middleware.LoggerFrom(c).Errorf("failed to save refresh token with error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	pair := TokenPair{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(expiresAt.Sub(now).Seconds()),
+	}
+	if r.setCookies {
+		if r.authentication != nil {
+			if err = r.authentication.SetAccessTokenCookie(c, accessToken, expiresAt); err != nil {
+				middleware.LoggerFrom(c).Errorf("failed to set access token cookie with error: %v", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+		} else {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(r.cookieName, accessToken, int(pair.ExpiresIn), "/", "", true, true)
+		}
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(r.refreshCookieName, refreshToken, int(r.refreshTokenTTL.Seconds()), r.refreshCookiePath, "", true, true)
+	} else {
+		pair.RefreshToken = refreshToken
+	}
+
+	// The tokens are the point of the response, they must not be redacted by middleware.ResponseWrapperMiddleware.
+	middleware.DisableRedaction(c)
+	c.JSON(http.StatusOK, pair)
+}
+
+func (r *tokenRotation) fail(c *gin.Context, err error) {
+	if errors.Is(err, RefreshTokenNotFoundError) || errors.Is(err, RefreshTokenExpiredError) {
+		r.clearCookies(c)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, r.errorResponse)
+		return
+	}
+	if errors.Is(err, RefreshTokenReusedError) {
+		r.clearCookies(c)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, r.tokenReuseResponse)
+		return
+	}
+
+	middleware.LoggerFrom(c).Errorf("failed to use refresh token with error: %v", err)
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+func (r *tokenRotation) clearCookies(c *gin.Context) {
+	if !r.setCookies {
+		return
+	}
+
+	if r.authentication != nil {
+		r.authentication.ClearAccessTokenCookie(c)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(r.cookieName, "", -1, "/", "", true, true)
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(r.refreshCookieName, "", -1, r.refreshCookiePath, "", true, true)
+}
+
+// newRefreshToken returns a random, URL-safe token of 256 bits.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package authentication
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RefreshToken is the stored record of a refresh token. Tokens rotated from one another share their FamilyID.
+type RefreshToken struct {
+	// ID is the SHA-256 hash of the token, so the store never holds usable tokens.
+	ID        string
+	FamilyID  string
+	Subject   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// RefreshTokenStore stores refresh tokens for TokenRotation.
+type RefreshTokenStore interface {
+	Save(ctx context.Context, t RefreshToken) error
+	// Use atomically marks the token as used and returns its record as it was before, so a record with Used set
+	// means the token is being reused. Returns RefreshTokenNotFoundError for unknown or revoked tokens.
+	Use(ctx context.Context, id string) (RefreshToken, error)
+	// RevokeFamily removes all the tokens of the family.
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+type memoryRefreshTokenStore struct {
+	mu       sync.Mutex
+	tokens   map[string]RefreshToken
+	families map[string][]string
+	now      func() time.Time
+}
+
+// NewMemoryRefreshTokenStore returns a RefreshTokenStore keeping the tokens in memory, for tests and single instance
+// deployments. Expired tokens are removed as new ones are saved.
+func NewMemoryRefreshTokenStore() RefreshTokenStore {
+	return &memoryRefreshTokenStore{
+		tokens:   map[string]RefreshToken{},
+		families: map[string][]string{},
+		now:      time.Now,
+	}
+}
+
+func (s *memoryRefreshTokenStore) Save(_ context.Context, t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeExpired()
+
+	s.tokens[t.ID] = t
+	s.families[t.FamilyID] = append(s.families[t.FamilyID], t.ID)
+
+	return nil
+}
+
+func (s *memoryRefreshTokenStore) Use(_ context.Context, id string) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tokens[id]
+	if !exists {
+		return RefreshToken{}, RefreshTokenNotFoundError
+	}
+
+	used := t
+	used.Used = true
+	s.tokens[id] = used
+
+	return t, nil
+}
+
+func (s *memoryRefreshTokenStore) RevokeFamily(_ context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.families[familyID] {
+		delete(s.tokens, id)
+	}
+	delete(s.families, familyID)
+
+	return nil
+}
+
+// removeExpired removes the families whose tokens have all expired, keeping used tokens of live families for
+// reuse detection.
+func (s *memoryRefreshTokenStore) removeExpired() {
+	now := s.now()
+	for familyID, ids := range s.families {
+		live := false
+		for _, id := range ids {
+			if s.tokens[id].ExpiresAt.After(now) {
+				live = true
+				break
+			}
+		}
+		if live {
+			continue
+		}
+
+		for _, id := range ids {
+			delete(s.tokens, id)
+		}
+		delete(s.families, familyID)
+	}
+}
@@ -0,0 +1,239 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Novometrix/util/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testIssuer = AccessTokenIssuerFunc(func(ctx context.Context, subject string) (string, time.Time, error) {
+	return testJWTString, time.Now().Add(15 * time.Minute), nil
+})
+
+func newRotationRouter(rotation TokenRotation, middlewares ...gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares...)
+	r.POST("/login", func(c *gin.Context) {
+		rotation.Issue(c, testUsername)
+	})
+	r.POST("/refresh", rotation.RefreshHandler())
+	r.POST("/logout", rotation.RevokeHandler())
+
+	return r
+}
+
+func serveRotation(r *gin.Engine, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestTokenRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("issue and rotate", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore(), WithRotationCookieName(cookieStr)))
+
+		w := serveRotation(r, "/login")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var pair TokenPair
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pair))
+		assert.Equal(t, testJWTString, pair.AccessToken)
+		assert.Equal(t, "Bearer", pair.TokenType)
+		assert.Empty(t, pair.RefreshToken)
+
+		access := responseCookie(w, cookieStr)
+		if assert.NotNil(t, access) {
+			assert.Equal(t, testJWTString, access.Value)
+			assert.True(t, access.HttpOnly)
+			assert.True(t, access.Secure)
+		}
+		refresh := responseCookie(w, "refresh-token")
+		if !assert.NotNil(t, refresh) {
+			return
+		}
+
+		w = serveRotation(r, "/refresh", refresh)
+		assert.Equal(t, http.StatusOK, w.Code)
+		rotated := responseCookie(w, "refresh-token")
+		if assert.NotNil(t, rotated) {
+			assert.NotEqual(t, refresh.Value, rotated.Value)
+		}
+	})
+
+	t.Run("reuse revokes family", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore()))
+
+		refresh := responseCookie(serveRotation(r, "/login"), "refresh-token")
+		rotated := responseCookie(serveRotation(r, "/refresh", refresh), "refresh-token")
+
+		w := serveRotation(r, "/refresh", refresh)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"error":"refresh token reused"}`, w.Body.String())
+		if cleared := responseCookie(w, "refresh-token"); assert.NotNil(t, cleared) {
+			assert.Empty(t, cleared.Value)
+		}
+
+		w = serveRotation(r, "/refresh", rotated)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.JSONEq(t, `{"error":"Unauthorized"}`, w.Body.String())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		rotation := NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore())
+		r := newRotationRouter(rotation)
+
+		refresh := responseCookie(serveRotation(r, "/login"), "refresh-token")
+		rotation.(*tokenRotation).now = func() time.Time {
+			return time.Now().Add(31 * 24 * time.Hour)
+		}
+
+		w := serveRotation(r, "/refresh", refresh)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("body tokens without cookies", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore(), WithRotationCookies(false)))
+
+		w := serveRotation(r, "/login")
+		assert.Empty(t, w.Result().Cookies())
+
+		var pair TokenPair
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pair))
+		assert.NotEmpty(t, pair.RefreshToken)
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(url.Values{"refresh_token": {pair.RefreshToken}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("through response wrapper", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore(), WithRotationCookies(false)), middleware.ResponseWrapperMiddleware())
+
+		w := serveRotation(r, "/login")
+
+		var resp middleware.BaseResponse[TokenPair]
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, testJWTString, resp.Payload.AccessToken)
+		assert.Equal(t, "Bearer", resp.Payload.TokenType)
+		assert.NotEmpty(t, resp.Payload.RefreshToken)
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(url.Values{"refresh_token": {resp.Payload.RefreshToken}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.ServeHTTP(w, req)
+
+		refreshed := resp.Payload.RefreshToken
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, testJWTString, resp.Payload.AccessToken)
+		assert.NotEmpty(t, resp.Payload.RefreshToken)
+		assert.NotEqual(t, refreshed, resp.Payload.RefreshToken)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore()))
+
+		refresh := responseCookie(serveRotation(r, "/login"), "refresh-token")
+
+		w := serveRotation(r, "/logout", refresh)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serveRotation(r, "/refresh", refresh)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("csrf protected", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore()), CSRFMiddleware())
+
+		refresh := responseCookie(serveRotation(r, "/login"), "refresh-token")
+
+		w := serveRotation(r, "/refresh", refresh)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serveRotation(r, "/logout", refresh)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+		req.Header.Set(CSRFHeader, testCSRFToken)
+		req.AddCookie(refresh)
+		req.AddCookie(&http.Cookie{Name: "csrf-token", Value: testCSRFToken})
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		r := newRotationRouter(NewTokenRotation(testIssuer, NewMemoryRefreshTokenStore()))
+
+		w := serveRotation(r, "/refresh")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("issuer error", func(t *testing.T) {
+		issuer := AccessTokenIssuerFunc(func(ctx context.Context, subject string) (string, time.Time, error) {
+			return "", time.Time{}, testError
+		})
+		r := newRotationRouter(NewTokenRotation(issuer, NewMemoryRefreshTokenStore()))
+
+		w := serveRotation(r, "/login")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestMemoryRefreshTokenStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	store := NewMemoryRefreshTokenStore()
+	store.(*memoryRefreshTokenStore).now = func() time.Time {
+		return now
+	}
+
+	assert.NoError(t, store.Save(ctx, RefreshToken{ID: "a", FamilyID: "f1", ExpiresAt: now.Add(-time.Minute)}))
+	assert.NoError(t, store.Save(ctx, RefreshToken{ID: "b", FamilyID: "f2", ExpiresAt: now.Add(time.Minute)}))
+
+	// Saving removed the expired family.
+	_, err := store.Use(ctx, "a")
+	assert.True(t, errors.Is(err, RefreshTokenNotFoundError))
+
+	token, err := store.Use(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, token.Used)
+
+	token, err = store.Use(ctx, "b")
+	assert.NoError(t, err)
+	assert.True(t, token.Used)
+
+	assert.NoError(t, store.RevokeFamily(ctx, "f2"))
+	_, err = store.Use(ctx, "b")
+	assert.True(t, errors.Is(err, RefreshTokenNotFoundError))
+}
@@ -0,0 +1,195 @@
+package authentication
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"sync"
+	"time"
+)
+
+const (
+	revokedTokenKeyPrefix   = "jti:"
+	revokedSubjectKeyPrefix = "sub:"
+)
+
+// RevocationCheck reports whether the validated claims of an access token are revoked, see WithRevocationCheck.
+type RevocationCheck func(ctx context.Context, claims jwt.Claims) (bool, error)
+
+// RevocationStore stores the revocations of a Revocation as expiring keys.
+type RevocationStore interface {
+	// Set stores value under key until ttl has elapsed.
+	Set(ctx context.Context, key string, value int64, ttl time.Duration) error
+	// Get returns the value under key, and whether there is one.
+	Get(ctx context.Context, key string) (int64, bool, error)
+}
+
+// RevocationKeyLister is implemented by the RevocationStores able to list their keys, which the bloom filter of
+// NewBloomRevocationStore is built from.
+type RevocationKeyLister interface {
+	Keys(ctx context.Context) ([]string, error)
+}
+
+type revocation struct {
+	store            RevocationStore
+	maxTokenLifetime time.Duration
+	now              func() time.Time
+}
+
+type Revocation interface {
+	// RevokeToken revokes the access token with the ID, its "jti" claim, until it expires.
+	RevokeToken(ctx context.Context, id string, expiresAt time.Time) error
+	// RevokeSubject revokes all the access tokens of the subject issued until now, e.g. to log out everywhere.
+	// Tokens issued within the same second are revoked as well, "iat" having a precision of seconds.
+	RevokeSubject(ctx context.Context, subject string) error
+	// IsRevoked is the RevocationCheck of the revocations. Tokens without "jti" can only be revoked by subject,
+	// and tokens without "iat" are revoked with any revocation of their subject.
+	IsRevoked(ctx context.Context, claims jwt.Claims) (bool, error)
+}
+
+// NewRevocation returns a Revocation keeping the revocations in store.
+func NewRevocation(store RevocationStore, options ...func(*revocation)) Revocation {
+	r := &revocation{
+		store:            store,
+		maxTokenLifetime: 24 * time.Hour,
+		now:              time.Now,
+	}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithMaxTokenLifetime sets how long subject revocations are kept, which must be at least the lifetime of the access
+// tokens. Defaults to 24 hours.
+func WithMaxTokenLifetime(d time.Duration) func(*revocation) {
+	return func(r *revocation) {
+		r.maxTokenLifetime = d
+	}
+}
+
+func (r *revocation) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(r.now())
+	if ttl <= 0 {
+		return nil
+	}
+
+	return r.store.Set(ctx, revokedTokenKeyPrefix+id, 1, ttl)
+}
+
+func (r *revocation) RevokeSubject(ctx context.Context, subject string) error {
+	return r.store.Set(ctx, revokedSubjectKeyPrefix+subject, r.now().Unix(), r.maxTokenLifetime)
+}
+
+func (r *revocation) IsRevoked(ctx context.Context, claims jwt.Claims) (bool, error) {
+	if id := tokenID(claims); id != "" {
+		_, revoked, err := r.store.Get(ctx, revokedTokenKeyPrefix+id)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return false, nil
+	}
+
+	revokedAt, revoked, err := r.store.Get(ctx, revokedSubjectKeyPrefix+subject)
+	if err != nil || !revoked {
+		return false, err
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return true, nil
+	}
+
+	return issuedAt.Unix() <= revokedAt, nil
+}
+
+// tokenID returns the "jti" claim of claims.
+func tokenID(claims jwt.Claims) string {
+	var m map[string]any
+	switch c := claims.(type) {
+	case jwt.MapClaims:
+		m = c
+	case *jwt.MapClaims:
+		m = *c
+	default:
+		var err error
+		if m, err = toMap(claims); err != nil {
+			return ""
+		}
+	}
+
+	id, _ := m["jti"].(string)
+	return id
+}
+
+type memoryRevocationEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+type memoryRevocationStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryRevocationEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRevocationStore returns a RevocationStore keeping the revocations in memory, for tests and single instance
+// deployments. Expired revocations are removed at most once a minute as new ones are stored.
+func NewMemoryRevocationStore() RevocationStore {
+	return &memoryRevocationStore{
+		entries: map[string]memoryRevocationEntry{},
+		now:     time.Now,
+	}
+}
+
+func (s *memoryRevocationStore) Set(_ context.Context, key string, value int64, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if !e.expiresAt.After(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.entries[key] = memoryRevocationEntry{value: value, expiresAt: now.Add(ttl)}
+
+	return nil
+}
+
+func (s *memoryRevocationStore) Get(_ context.Context, key string) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[key]
+	if !exists || !e.expiresAt.After(s.now()) {
+		return 0, false, nil
+	}
+
+	return e.value, true, nil
+}
+
+func (s *memoryRevocationStore) Keys(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	keys := make([]string, 0, len(s.entries))
+	for k, e := range s.entries {
+		if e.expiresAt.After(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,207 @@
+package authentication
+
+import (
+	"context"
+	"github.com/Novometrix/util/middleware"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newBloomFilter returns a bloom filter sized for n keys with the false positive rate p.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (f *bloomFilter) add(key string) {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHashes returns the two hashes the k hashes of key are derived from, by double hashing.
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+
+	return sum & math.MaxUint32, sum>>32 | 1
+}
+
+type bloomRevocationStore struct {
+	store     RevocationStore
+	refreshMu sync.Mutex
+
+	mu                sync.RWMutex
+	filter            *bloomFilter
+	refreshing        bool
+	setDuringRefresh  []string
+	capacity          int
+	falsePositiveRate float64
+}
+
+type BloomRevocationStore interface {
+	RevocationStore
+	// Refresh rebuilds the filter from the keys of the underlying store.
+	Refresh(ctx context.Context) error
+	// RefreshEvery refreshes the filter at the interval until ctx is done, logging failures.
+	RefreshEvery(ctx context.Context, interval time.Duration)
+}
+
+// NewBloomRevocationStore returns a RevocationStore looking keys up in store only if a bloom filter of the revoked
+// keys may contain them, so that checking tokens which are not revoked, nearly all of them, costs no round trip.
+// The filter is built from the keys of store, which must be a RevocationKeyLister, otherwise StoreNotListableError is
+// returned, as an empty filter would report revoked keys as unknown. The filter learns the keys set through it, while
+// keys set by other instances are only known once it is refreshed, see BloomRevocationStore.RefreshEvery.
+func NewBloomRevocationStore(ctx context.Context, store RevocationStore, options ...func(*bloomRevocationStore)) (BloomRevocationStore, error) {
+	s := &bloomRevocationStore{
+		store:             store,
+		capacity:          10000,
+		falsePositiveRate: 0.01,
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	s.filter = newBloomFilter(s.capacity, s.falsePositiveRate)
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// WithBloomCapacity sets the number of keys the filter is sized for and its false positive rate. The filter grows
+// on refresh if the store holds more keys. Defaults to 10000 keys and 1%.
+func WithBloomCapacity(n int, falsePositiveRate float64) func(*bloomRevocationStore) {
+	return func(s *bloomRevocationStore) {
+		s.capacity = n
+		s.falsePositiveRate = falsePositiveRate
+	}
+}
+
+func (s *bloomRevocationStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	if err := s.store.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.filter.add(key)
+	if s.refreshing {
+		s.setDuringRefresh = append(s.setDuringRefresh, key)
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *bloomRevocationStore) Get(ctx context.Context, key string) (int64, bool, error) {
+	s.mu.RLock()
+	mayContain := s.filter.mayContain(key)
+	s.mu.RUnlock()
+
+	if !mayContain {
+		return 0, false, nil
+	}
+
+	return s.store.Get(ctx, key)
+}
+
+func (s *bloomRevocationStore) Refresh(ctx context.Context) error {
+	lister, ok := s.store.(RevocationKeyLister)
+	if !ok {
+		return StoreNotListableError
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	// Keys set while the store is listed may be missing from the listing, they are added to the new filter as well.
+	s.mu.Lock()
+	s.refreshing = true
+	s.setDuringRefresh = nil
+	s.mu.Unlock()
+
+	keys, err := lister.Keys(ctx)
+	if err != nil {
+		s.mu.Lock()
+		s.refreshing = false
+		s.mu.Unlock()
+		return err
+	}
+
+	n := s.capacity
+	if 2*len(keys) > n {
+		n = 2 * len(keys)
+	}
+	filter := newBloomFilter(n, s.falsePositiveRate)
+	for _, key := range keys {
+		filter.add(key)
+	}
+
+	s.mu.Lock()
+	for _, key := range s.setDuringRefresh {
+		filter.add(key)
+	}
+	s.filter = filter
+	s.refreshing = false
+	s.setDuringRefresh = nil
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *bloomRevocationStore) RefreshEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				middleware.LoggerFrom(ctx).Errorf("failed to refresh revocation bloom filter with error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRevocationStore struct {
+	RevocationStore
+	gets int
+}
+
+func (s *countingRevocationStore) Get(ctx context.Context, key string) (int64, bool, error) {
+	s.gets++
+	return s.RevocationStore.Get(ctx, key)
+}
+
+func (s *countingRevocationStore) Keys(ctx context.Context) ([]string, error) {
+	return s.RevocationStore.(RevocationKeyLister).Keys(ctx)
+}
+
+func TestBloomFilter(t *testing.T) {
+	f := newBloomFilter(1000, 0.01)
+
+	for i := 0; i < 1000; i++ {
+		f.add(fmt.Sprintf("key-%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		assert.True(t, f.mayContain(fmt.Sprintf("key-%d", i)))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.mayContain(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestBloomRevocationStore(t *testing.T) {
+	ctx := context.Background()
+
+	inner := &countingRevocationStore{RevocationStore: NewMemoryRevocationStore()}
+	assert.NoError(t, inner.Set(ctx, "existing", 1, time.Minute))
+
+	s, err := NewBloomRevocationStore(ctx, inner, WithBloomCapacity(100, 0.001))
+	assert.NoError(t, err)
+
+	// Keys of the store are known from the start.
+	_, ok, err := s.Get(ctx, "existing")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, inner.gets)
+
+	// Unknown keys do not reach the store.
+	_, ok, err = s.Get(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, inner.gets)
+
+	assert.NoError(t, s.Set(ctx, "new", 2, time.Minute))
+	v, ok, err := s.Get(ctx, "new")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, v)
+
+	// Keys set by other instances are known after a refresh.
+	assert.NoError(t, inner.Set(ctx, "other-instance", 3, time.Minute))
+	_, ok, _ = s.Get(ctx, "other-instance")
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Refresh(ctx))
+	_, ok, _ = s.Get(ctx, "other-instance")
+	assert.True(t, ok)
+}
+
+func TestBloomRevocationStoreNotListable(t *testing.T) {
+	// The store hides the Keys method of the memory store.
+	store := struct{ RevocationStore }{NewMemoryRevocationStore()}
+
+	_, err := NewBloomRevocationStore(context.Background(), store)
+	assert.ErrorIs(t, err, StoreNotListableError)
+}
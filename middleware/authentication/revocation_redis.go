@@ -0,0 +1,291 @@
+package authentication
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisError is an error reply of the server, after which the connection remains usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+type redisRevocationStore struct {
+	addr        string
+	password    string
+	db          int
+	keyPrefix   string
+	dialTimeout time.Duration
+	// timeout bounds the commands sent with a context without deadline, such as a *gin.Context.
+	timeout time.Duration
+	conns   chan *redisConn
+}
+
+type RedisRevocationStore interface {
+	RevocationStore
+	RevocationKeyLister
+	io.Closer
+}
+
+// NewRedisRevocationStore returns a RevocationStore keeping the revocations in the Redis server, or any server
+// speaking its protocol, at addr. Connections are dialed on demand and up to 8 idle ones are kept, see
+// WithRedisMaxIdleConns. Commands time out after the deadline of their context or, if it has none, after 1 second,
+// see WithRedisTimeout.
+func NewRedisRevocationStore(addr string, options ...func(*redisRevocationStore)) RedisRevocationStore {
+	s := &redisRevocationStore{
+		addr:        addr,
+		keyPrefix:   "revoked:",
+		dialTimeout: 5 * time.Second,
+		timeout:     time.Second,
+		conns:       make(chan *redisConn, 8),
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithRedisPassword(p string) func(*redisRevocationStore) {
+	return func(s *redisRevocationStore) {
+		s.password = p
+	}
+}
+
+func WithRedisDB(db int) func(*redisRevocationStore) {
+	return func(s *redisRevocationStore) {
+		s.db = db
+	}
+}
+
+// WithRedisKeyPrefix sets the prefix of the keys of the store. Defaults to "revoked:".
+func WithRedisKeyPrefix(p string) func(*redisRevocationStore) {
+	return func(s *redisRevocationStore) {
+		s.keyPrefix = p
+	}
+}
+
+// WithRedisTimeout sets the time after which the commands sent with a context without deadline are given up,
+// failing the revocation check. Defaults to 1 second.
+func WithRedisTimeout(d time.Duration) func(*redisRevocationStore) {
+	return func(s *redisRevocationStore) {
+		s.timeout = d
+	}
+}
+
+func WithRedisMaxIdleConns(n int) func(*redisRevocationStore) {
+	return func(s *redisRevocationStore) {
+		s.conns = make(chan *redisConn, n)
+	}
+}
+
+func (s *redisRevocationStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	_, err := s.do(ctx, "SET", s.keyPrefix+key, strconv.FormatInt(value, 10), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+func (s *redisRevocationStore) Get(ctx context.Context, key string) (int64, bool, error) {
+	reply, err := s.do(ctx, "GET", s.keyPrefix+key)
+	if err != nil || reply == nil {
+		return 0, false, err
+	}
+
+	b, ok := reply.([]byte)
+	if !ok {
+		return 0, false, fmt.Errorf("redis: unexpected reply %T to GET", reply)
+	}
+
+	value, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return value, true, nil
+}
+
+func (s *redisRevocationStore) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, "SCAN", cursor, "MATCH", s.keyPrefix+"*", "COUNT", "1000")
+		if err != nil {
+			return nil, err
+		}
+
+		r, ok := reply.([]any)
+		if !ok || len(r) != 2 {
+			return nil, fmt.Errorf("redis: unexpected reply %v to SCAN", reply)
+		}
+		next, _ := r[0].([]byte)
+		batch, _ := r[1].([]any)
+		for _, k := range batch {
+			if b, ok := k.([]byte); ok {
+				keys = append(keys, strings.TrimPrefix(string(b), s.keyPrefix))
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+// Close closes the idle connections.
+func (s *redisRevocationStore) Close() error {
+	for {
+		select {
+		case conn := <-s.conns:
+			_ = conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends the command and returns its reply: a string, an int64, a []byte, a []any or nil.
+func (s *redisRevocationStore) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.SetDeadline(s.deadline(ctx)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	select {
+	case s.conns <- conn:
+	default:
+		_ = conn.Close()
+	}
+
+	return reply, err
+}
+
+func (s *redisRevocationStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.conns:
+		return conn, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: s.dialTimeout}
+	c, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: c, r: bufio.NewReader(c)}
+
+	_ = conn.SetDeadline(s.deadline(ctx))
+	if s.password != "" {
+		if _, err = conn.do("AUTH", s.password); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err = conn.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// deadline returns the deadline of ctx, or the one of the default timeout if it has none.
+func (s *redisRevocationStore) deadline(ctx context.Context) time.Time {
+	if deadline, ok := ctx.Deadline(); ok {
+		return deadline
+	}
+
+	return time.Now().Add(s.timeout)
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(c.Conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return readRedisReply(c.r)
+}
+
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		elems := make([]any, n)
+		for i := range elems {
+			var replyErr redisError
+			if elems[i], err = readRedisReply(r); err != nil && !errors.As(err, &replyErr) {
+				return nil, err
+			}
+		}
+		return elems, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
@@ -0,0 +1,224 @@
+package authentication
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a stand-in for a Redis server, implementing the commands of the revocation store.
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	values   map[string]string
+	expiry   map[string]time.Time
+}
+
+func startFakeRedis(t *testing.T, password string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	f := &fakeRedis{password: password, values: map[string]string{}, expiry: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	for {
+		args, err := readFakeRedisCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		if cmd == "AUTH" {
+			if args[1] != f.password {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authenticated = true
+			io.WriteString(conn, "+OK\r\n")
+			continue
+		}
+		if !authenticated {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		io.WriteString(conn, f.exec(cmd, args[1:]))
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for k, exp := range f.expiry {
+		if time.Now().After(exp) {
+			delete(f.values, k)
+			delete(f.expiry, k)
+		}
+	}
+
+	switch cmd {
+	case "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		f.values[args[0]] = args[1]
+		if len(args) == 4 && strings.ToUpper(args[2]) == "PX" {
+			ms, _ := strconv.Atoi(args[3])
+			f.expiry[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.values[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SCAN":
+		prefix := strings.TrimSuffix(args[2], "*")
+		var keys []string
+		for k := range f.values {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		reply := fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, k := range keys {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(k), k)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + cmd + "'\r\n"
+	}
+}
+
+func readFakeRedisCommand(r *bufio.Reader) ([]string, error) {
+	reply, err := readRedisReply(r)
+	if err != nil {
+		return nil, err
+	}
+
+	elems, ok := reply.([]any)
+	if !ok || len(elems) == 0 {
+		return nil, fmt.Errorf("unexpected command %v", reply)
+	}
+	args := make([]string, len(elems))
+	for i, e := range elems {
+		b, _ := e.([]byte)
+		args[i] = string(b)
+	}
+
+	return args, nil
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	addr := startFakeRedis(t, testStringNoWhitespace)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewRedisRevocationStore(addr, WithRedisPassword(testStringNoWhitespace), WithRedisDB(1))
+	defer s.Close()
+
+	assert.NoError(t, s.Set(ctx, "a", 42, time.Minute))
+	assert.NoError(t, s.Set(ctx, "b", 1, time.Millisecond))
+
+	v, ok, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualValues(t, 42, v)
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok, err = s.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	keys, err := s.Keys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+
+	r := NewRevocation(s)
+	assert.NoError(t, r.RevokeSubject(ctx, testUsername))
+	revoked, err := r.IsRevoked(ctx, &testClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: testUsername}})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestRedisRevocationStoreErrors(t *testing.T) {
+	addr := startFakeRedis(t, testStringNoWhitespace)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewRedisRevocationStore(addr, WithRedisPassword("wrong"))
+	defer s.Close()
+
+	_, _, err := s.Get(ctx, "a")
+	assert.ErrorContains(t, err, "WRONGPASS")
+
+	s = NewRedisRevocationStore("127.0.0.1:1")
+	_, _, err = s.Get(ctx, "a")
+	assert.Error(t, err)
+}
+
+func TestRedisRevocationStoreTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer l.Close()
+
+	// The server accepts connections but never replies.
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				_ = conn.Close()
+			})
+		}
+	}()
+
+	s := NewRedisRevocationStore(l.Addr().String(), WithRedisTimeout(50*time.Millisecond))
+	defer s.Close()
+
+	start := time.Now()
+	_, _, err = s.Get(context.Background(), "a")
+
+	var netErr net.Error
+	assert.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,161 @@
+package authentication
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ssw "github.com/RaymondSalim/ssw-go-jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRevocation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	r := NewRevocation(NewMemoryRevocationStore())
+
+	assert.NoError(t, r.RevokeToken(ctx, "revoked", now.Add(time.Hour)))
+	assert.NoError(t, r.RevokeToken(ctx, "expired", now.Add(-time.Hour)))
+	assert.NoError(t, r.RevokeSubject(ctx, testUsername))
+
+	tests := []struct {
+		name    string
+		claims  jwt.Claims
+		revoked bool
+	}{
+		{
+			name:    "revoked jti",
+			claims:  &jwt.MapClaims{"jti": "revoked", "sub": "someone"},
+			revoked: true,
+		},
+		{
+			name:   "expired token not stored",
+			claims: &jwt.MapClaims{"jti": "expired", "sub": "someone"},
+		},
+		{
+			name:   "other jti",
+			claims: &jwt.MapClaims{"jti": "other", "sub": "someone"},
+		},
+		{
+			name:    "typed claims",
+			claims:  &testClaims{RegisteredClaims: jwt.RegisteredClaims{ID: "revoked"}},
+			revoked: true,
+		},
+		{
+			name:    "subject issued before revocation",
+			claims:  &jwt.MapClaims{"sub": testUsername, "iat": float64(now.Add(-time.Minute).Unix())},
+			revoked: true,
+		},
+		{
+			name:   "subject issued after revocation",
+			claims: &jwt.MapClaims{"sub": testUsername, "iat": float64(now.Add(time.Minute).Unix())},
+		},
+		{
+			name:    "subject without iat",
+			claims:  &jwt.MapClaims{"sub": testUsername},
+			revoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := r.IsRevoked(ctx, tt.claims)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.revoked, revoked)
+		})
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	s := NewMemoryRevocationStore()
+	s.(*memoryRevocationStore).now = func() time.Time {
+		return now
+	}
+
+	assert.NoError(t, s.Set(ctx, "a", 1, time.Minute))
+	assert.NoError(t, s.Set(ctx, "b", 2, -time.Minute))
+
+	v, ok, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, v)
+
+	_, ok, err = s.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	keys, err := s.(RevocationKeyLister).Keys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+}
+
+func TestRevocationCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		check      RevocationCheck
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "not revoked",
+			check: func(ctx context.Context, claims jwt.Claims) (bool, error) {
+				return false, nil
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "revoked",
+			check: func(ctx context.Context, claims jwt.Claims) (bool, error) {
+				return true, nil
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"access token revoked"}`,
+		},
+		{
+			name: "check failure",
+			check: func(ctx context.Context, claims jwt.Claims) (bool, error) {
+				return false, testError
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"Unauthorized"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sswMock := ssw.NewMockSSWGoJWT(t)
+			sswMock.On("ValidateAccessTokenWithClaims", testJWTString, mock.AnythingOfType("*jwt.MapClaims")).Return(nil).Once()
+
+			var goJWT ssw.SSWGoJWT = sswMock
+			mw := NewAuthenticationMiddleware(&goJWT, WithRevocationCheck(tt.check))
+
+			r := gin.New()
+			r.Use(mw.RequireAuthenticatedMiddleware())
+			r.GET("/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+testJWTString)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,15 @@
 package authentication
 
+import "github.com/gin-gonic/gin"
+
 type AuthenticationType int
 
+// TokenSource is the part of the request an access token was extracted from.
+type TokenSource string
+
+// TokenExtractor returns the access token of the request and its source, or an empty token if the request has none.
+type TokenExtractor func(c *gin.Context) (string, TokenSource)
+
 type response struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,79 @@
+package authentication
+
+import (
+	"fmt"
+	"github.com/Novometrix/util/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verifier validates access tokens, decoding their claims into claims. ssw.SSWGoJWT is a Verifier.
+type Verifier interface {
+	ValidateAccessTokenWithClaims(token string, claims jwt.Claims) error
+}
+
+// IssuerConfig configures the validation of the tokens of an issuer for NewMultiIssuerVerifier.
+type IssuerConfig struct {
+	// Issuer is the "iss" claim of the tokens of the issuer.
+	Issuer string
+	// Audiences, if not empty, are the audiences of which the "aud" claim must contain one.
+	Audiences []string
+	// Verifier validates the tokens of the issuer, e.g. with the keys of its JWKS.
+	Verifier Verifier
+}
+
+type multiIssuerVerifier struct {
+	issuers map[string]IssuerConfig
+	parser  *jwt.Parser
+}
+
+// NewMultiIssuerVerifier returns a Verifier routing tokens to the configuration of their issuer, read from the
+// unverified token, and validating their issuer and audience once verified. Tokens of other issuers are rejected.
+func NewMultiIssuerVerifier(issuers ...IssuerConfig) Verifier {
+	v := &multiIssuerVerifier{
+		issuers: make(map[string]IssuerConfig, len(issuers)),
+		parser:  jwt.NewParser(),
+	}
+
+	for _, cfg := range issuers {
+		v.issuers[cfg.Issuer] = cfg
+	}
+
+	return v
+}
+
+func (v *multiIssuerVerifier) ValidateAccessTokenWithClaims(token string, claims jwt.Claims) error {
+	unverified := jwt.MapClaims{}
+	if _, _, err := v.parser.ParseUnverified(token, unverified); err != nil {
+		return err
+	}
+
+	iss, _ := unverified.GetIssuer()
+	cfg, exists := v.issuers[iss]
+	if !exists {
+		return fmt.Errorf("%w: unknown issuer %q", jwt.ErrTokenInvalidIssuer, iss)
+	}
+
+	if err := cfg.Verifier.ValidateAccessTokenWithClaims(token, claims); err != nil {
+		return err
+	}
+
+	// The verified claims are checked again, the routing having relied on unverified ones.
+	if verified, err := claims.GetIssuer(); err != nil || verified != cfg.Issuer {
+		return jwt.ErrTokenInvalidIssuer
+	}
+
+	if len(cfg.Audiences) > 0 {
+		aud, err := claims.GetAudience()
+		if err != nil {
+			return jwt.ErrTokenInvalidAudience
+		}
+		for _, a := range aud {
+			if util.SliceContains(cfg.Audiences, a) {
+				return nil
+			}
+		}
+		return jwt.ErrTokenInvalidAudience
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiIssuerVerifier(t *testing.T) {
+	keys := newTestJWKSKeys(t)
+	dir := t.TempDir()
+
+	newVerifier := func(name string, k testJWKSKey) Verifier {
+		path := filepath.Join(dir, name+".json")
+		assert.NoError(t, os.WriteFile(path, testJWKS(t, k), 0o600))
+
+		v, err := NewJWKSVerifier(context.Background(), JWKSFromFile(path))
+		assert.NoError(t, err)
+
+		return v
+	}
+
+	v := NewMultiIssuerVerifier(
+		IssuerConfig{Issuer: "https://a.example", Audiences: []string{"api"}, Verifier: newVerifier("a", keys[0])},
+		IssuerConfig{Issuer: "https://b.example", Verifier: newVerifier("b", keys[1])},
+	)
+
+	claims := func(iss string, aud ...string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    iss,
+			Audience:  aud,
+			Subject:   testUsername,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "issuer a",
+			token: signTestToken(t, keys[0], claims("https://a.example", "web", "api")),
+		},
+		{
+			name:  "issuer b without audience",
+			token: signTestToken(t, keys[1], claims("https://b.example")),
+		},
+		{
+			name:    "issuer a wrong audience",
+			token:   signTestToken(t, keys[0], claims("https://a.example", "web")),
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:    "issuer a signed with key of b",
+			token:   signTestToken(t, testJWKSKey{kid: keys[0].kid, method: keys[1].method, key: keys[1].key}, claims("https://a.example", "api")),
+			wantErr: jwt.ErrTokenUnverifiable,
+		},
+		{
+			name:    "unknown issuer",
+			token:   signTestToken(t, keys[0], claims("https://c.example", "api")),
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:    "malformed",
+			token:   testString,
+			wantErr: jwt.ErrTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := jwt.MapClaims{}
+			err := v.ValidateAccessTokenWithClaims(tt.token, &c)
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, testUsername, c["sub"])
+			} else {
+				assert.True(t, errors.Is(err, tt.wantErr), "%v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+)
+
+// cborEncoder writes CBOR, see RFC 8949.
+type cborEncoder struct{}
+
+func (cborEncoder) ContentType() string {
+	return MediaTypeCBOR
+}
+
+func (cborEncoder) Encode(v any) ([]byte, error) {
+	t, err := toValueTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = writeCBOR(&buf, t)
+	return buf.Bytes(), err
+}
+
+func writeCBOR(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case int64:
+		if v >= 0 {
+			writeCBORHeader(buf, cborUnsigned, uint64(v))
+		} else {
+			writeCBORHeader(buf, cborNegative, uint64(-1-v))
+		}
+	case uint64:
+		writeCBORHeader(buf, cborUnsigned, v)
+	case float64:
+		buf.WriteByte(0xfb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeCBORHeader(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		writeCBORHeader(buf, cborArray, uint64(len(v)))
+		for _, e := range v {
+			if err := writeCBOR(buf, e); err != nil {
+				return err
+			}
+		}
+	case object:
+		writeCBORHeader(buf, cborMap, uint64(len(v)))
+		for _, m := range v {
+			_ = writeCBOR(buf, m.key)
+			if err := writeCBOR(buf, m.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unsupported type %T", v)
+	}
+
+	return nil
+}
+
+func writeCBORHeader(buf *bytes.Buffer, major byte, arg uint64) {
+	major <<= 5
+
+	switch {
+	case arg < 24:
+		buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major | 27)
+		_ = binary.Write(buf, binary.BigEndian, arg)
+	}
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+
+	defaultMinCompressSize = 1024
+)
+
+// Compressor is a streaming compressor, e.g. *gzip.Writer.
+type Compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressorFactory returns a Compressor writing the compressed data to w.
+type CompressorFactory func(w io.Writer) (Compressor, error)
+
+type compressor struct {
+	encoding string
+	factory  CompressorFactory
+}
+
+type compression struct {
+	compressors  []compressor
+	minSize      int
+	contentTypes []string
+}
+
+// WithCompressor adds support for a content coding, or replaces the built-in compressor of one. Compressors added with
+// WithCompressor are preferred over the built-in brotli, gzip and deflate ones when the client accepts several content
+// codings with the same quality. For instance, zstd can be backed by github.com/klauspost/compress/zstd:
+//
+//	CompressionMiddleware(WithCompressor("zstd", func(w io.Writer) (Compressor, error) {
+//		return zstd.NewWriter(w)
+//	}))
+func WithCompressor(encoding string, factory CompressorFactory) func(*compression) {
+	return func(cm *compression) {
+		encoding = strings.ToLower(encoding)
+		for i, c := range cm.compressors {
+			if c.encoding == encoding {
+				cm.compressors = append(cm.compressors[:i], cm.compressors[i+1:]...)
+				break
+			}
+		}
+		cm.compressors = append([]compressor{{encoding: encoding, factory: factory}}, cm.compressors...)
+	}
+}
+
+// WithMinCompressSize sets the size in bytes under which responses are not compressed. Defaults to 1024.
+func WithMinCompressSize(n int) func(*compression) {
+	return func(cm *compression) {
+		cm.minSize = n
+	}
+}
+
+// WithCompressibleContentTypes sets the media types of the responses to be compressed.
+// A media type ending with "/" matches all of its subtypes, e.g. "text/".
+func WithCompressibleContentTypes(contentTypes ...string) func(*compression) {
+	return func(cm *compression) {
+		cm.contentTypes = contentTypes
+	}
+}
+
+// CompressionMiddleware compresses responses with the content coding negotiated from the Accept-Encoding header,
+// brotli, gzip or deflate by default, brotli being preferred when the client accepts several of them equally.
+// Responses are buffered until they reach the minimum size, and are written uncompressed if they never do.
+// The middleware must be registered before ResponseWrapperMiddleware, so that the wrapped response is compressed.
+func CompressionMiddleware(options ...func(*compression)) gin.HandlerFunc {
+	cm := &compression{
+		compressors: []compressor{
+			{encoding: EncodingBrotli, factory: func(w io.Writer) (Compressor, error) {
+				return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+			}},
+			{encoding: EncodingGzip, factory: func(w io.Writer) (Compressor, error) {
+				return gzip.NewWriter(w), nil
+			}},
+			{encoding: EncodingDeflate, factory: func(w io.Writer) (Compressor, error) {
+				return flate.NewWriter(w, flate.DefaultCompression)
+			}},
+		},
+		minSize: defaultMinCompressSize,
+		contentTypes: []string{
+			"text/",
+			MediaTypeJSON,
+			MediaTypeProtoJSON,
+			MediaTypeMsgpack,
+			MediaTypeCBOR,
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+		},
+	}
+
+	for _, opt := range options {
+		opt(cm)
+	}
+
+	return func(c *gin.Context) {
+		comp, ok := cm.negotiate(c.GetHeader("Accept-Encoding"))
+		if !ok || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			compression:    cm,
+			compressor:     comp,
+		}
+		c.Writer = cw
+		defer cw.close()
+
+		c.Next()
+	}
+}
+
+// negotiate returns the compressor with the highest quality in the Accept-Encoding header.
+func (cm *compression) negotiate(acceptEncoding string) (compressor, bool) {
+	qualities := map[string]float64{}
+	for _, e := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(e), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	var best compressor
+	bestQ := 0.0
+	for _, c := range cm.compressors {
+		q, found := qualities[c.encoding]
+		if !found {
+			q, found = qualities["*"]
+		}
+		if found && q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+func (cm *compression) isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range cm.contentTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	compression *compression
+	compressor  compressor
+
+	buf     bytes.Buffer
+	started bool
+	w       io.Writer
+	cw      Compressor
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.started {
+		return cw.w.Write(b)
+	}
+
+	n, _ := cw.buf.Write(b)
+	if cw.buf.Len() >= cw.compression.minSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+func (cw *compressWriter) WriteString(s string) (int, error) {
+	return cw.Write([]byte(s))
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if err := cw.start(true); err != nil {
+			log.Errorf("failed to start compression with error: %v", err)
+			return
+		}
+	}
+	if cw.cw != nil {
+		_ = cw.cw.Flush()
+	}
+
+	cw.ResponseWriter.Flush()
+}
+
+// start writes the headers and the buffered body, compressing them if sizeReached and the response is compressible.
+func (cw *compressWriter) start(sizeReached bool) error {
+	cw.started = true
+	cw.w = cw.ResponseWriter
+
+	h := cw.ResponseWriter.Header()
+	status := cw.ResponseWriter.Status()
+	compressible := cw.compression.isCompressible(h.Get("Content-Type")) &&
+		h.Get("Content-Encoding") == "" &&
+		status != http.StatusNoContent && status != http.StatusNotModified
+
+	if compressible {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	if compressible && sizeReached {
+		c, err := cw.compressor.factory(cw.ResponseWriter)
+		if err != nil {
+			return err
+		}
+
+		cw.cw = c
+		cw.w = c
+		h.Set("Content-Encoding", cw.compressor.encoding)
+		h.Del("Content-Length")
+		// The strong ETag of the uncompressed response cannot be used for the compressed one.
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+	} else if !sizeReached && cw.buf.Len() > 0 {
+		// The buffer only holds the whole body once the response is complete, later writes may follow otherwise.
+		h.Set("Content-Length", strconv.Itoa(cw.buf.Len()))
+	}
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := cw.w.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressWriter) close() {
+	if !cw.started {
+		if err := cw.start(false); err != nil {
+			log.Errorf("failed to write response with error: %v", err)
+			return
+		}
+	}
+
+	if cw.cw != nil {
+		if err := cw.cw.Close(); err != nil {
+			log.Errorf("failed to close compressor with error: %v", err)
+		}
+	}
+}
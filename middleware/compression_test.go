@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type nopCompressor struct {
+	io.Writer
+}
+
+func (nopCompressor) Close() error {
+	return nil
+}
+
+func (nopCompressor) Flush() error {
+	return nil
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat("a", 2048)
+
+	decompress := map[string]func(io.Reader) (io.Reader, error){
+		EncodingBrotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		EncodingGzip: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		EncodingDeflate: func(r io.Reader) (io.Reader, error) {
+			return flate.NewReader(r), nil
+		},
+		"identity": func(r io.Reader) (io.Reader, error) {
+			return r, nil
+		},
+		"custom": func(r io.Reader) (io.Reader, error) {
+			return r, nil
+		},
+	}
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		options          []func(*compression)
+		handler          gin.HandlerFunc
+		expectedEncoding string
+		vary             bool
+		unwrapped        bool
+	}{
+		{
+			name:             "success_gzip",
+			acceptEncoding:   "gzip, deflate",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: EncodingGzip,
+			vary:             true,
+		},
+		{
+			name:             "success_brotli_preferred",
+			acceptEncoding:   "gzip, deflate, br",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: EncodingBrotli,
+			vary:             true,
+		},
+		{
+			name:             "success_brotli_quality",
+			acceptEncoding:   "gzip;q=1.0, br;q=0.8",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: EncodingGzip,
+			vary:             true,
+		},
+		{
+			name:             "success_deflate_quality",
+			acceptEncoding:   "gzip;q=0.5, deflate",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: EncodingDeflate,
+			vary:             true,
+		},
+		{
+			name:             "success_custom_compressor_preferred",
+			acceptEncoding:   "gzip, custom",
+			options:          []func(*compression){WithCompressor("custom", func(w io.Writer) (Compressor, error) { return nopCompressor{w}, nil })},
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: "custom",
+			vary:             true,
+		},
+		{
+			name:             "success_below_min_size",
+			acceptEncoding:   "gzip",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, "small") },
+			expectedEncoding: "identity",
+			vary:             true,
+		},
+		{
+			name:             "success_min_size_option",
+			acceptEncoding:   "gzip",
+			options:          []func(*compression){WithMinCompressSize(1)},
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, "small") },
+			expectedEncoding: EncodingGzip,
+			vary:             true,
+		},
+		{
+			name:             "success_content_type_filtered",
+			acceptEncoding:   "gzip",
+			handler:          func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) },
+			expectedEncoding: "identity",
+			unwrapped:        true,
+		},
+		{
+			name:           "success_content_type_filtered_multiple_writes",
+			acceptEncoding: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Type", "image/png")
+				_, _ = c.Writer.Write([]byte(large[:1024]))
+				_, _ = c.Writer.Write([]byte(large[1024:]))
+			},
+			expectedEncoding: "identity",
+			unwrapped:        true,
+		},
+		{
+			name:           "success_content_type_filtered_flush",
+			acceptEncoding: "gzip",
+			handler: func(c *gin.Context) {
+				c.Header("Content-Type", "image/png")
+				_, _ = c.Writer.Write([]byte(large[:100]))
+				c.Writer.Flush()
+				_, _ = c.Writer.Write([]byte(large[100:]))
+			},
+			expectedEncoding: "identity",
+			unwrapped:        true,
+		},
+		{
+			name:             "success_content_type_option",
+			acceptEncoding:   "gzip",
+			options:          []func(*compression){WithCompressibleContentTypes("image/")},
+			handler:          func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) },
+			expectedEncoding: EncodingGzip,
+			vary:             true,
+			unwrapped:        true,
+		},
+		{
+			name:             "success_not_accepted",
+			acceptEncoding:   "compress, gzip;q=0",
+			handler:          func(c *gin.Context) { c.JSON(http.StatusOK, large) },
+			expectedEncoding: "identity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(CompressionMiddleware(tt.options...))
+			if !tt.unwrapped {
+				e.Use(ResponseWrapperMiddleware())
+			}
+			e.GET("/", tt.handler)
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			req.Header.Set(RequestIDHeader, TestID)
+			e.ServeHTTP(w, req)
+
+			encoding := w.Header().Get("Content-Encoding")
+			if encoding == "" {
+				encoding = "identity"
+				if l := w.Header().Get("Content-Length"); l != "" {
+					a.Equal(strconv.Itoa(w.Body.Len()), l)
+				}
+			} else {
+				a.Empty(w.Header().Get("Content-Length"))
+			}
+			a.Equal(tt.expectedEncoding, encoding)
+			a.Equal(tt.vary, strings.Contains(strings.Join(w.Header().Values("Vary"), ","), "Accept-Encoding"))
+
+			r, err := decompress[encoding](w.Body)
+			a.NoError(err)
+			body, err := io.ReadAll(r)
+			a.NoError(err)
+
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				var resp BaseResponse[string]
+				a.NoError(json.Unmarshal(body, &resp))
+				a.Equal(TestID, resp.RequestID)
+			} else {
+				a.True(bytes.Equal([]byte(large), body))
+			}
+		})
+	}
+}
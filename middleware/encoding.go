@@ -0,0 +1,335 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	MediaTypeJSON      = "application/json"
+	MediaTypeMsgpack   = "application/msgpack"
+	MediaTypeCBOR      = "application/cbor"
+	MediaTypeProtoJSON = "application/x-protobuf-json"
+
+	encoderContextKey = "github.com/Novometrix/util/middleware.encoder"
+)
+
+// Encoder serializes response envelopes into a media type.
+type Encoder interface {
+	// ContentType returns the value of the Content-Type header of encoded responses.
+	ContentType() string
+	Encode(v any) ([]byte, error)
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		MediaTypeJSON:               jsonEncoder{},
+		MediaTypeMsgpack:            msgpackEncoder{},
+		"application/x-msgpack":     msgpackEncoder{},
+		MediaTypeCBOR:               cborEncoder{},
+		MediaTypeProtoJSON:          protoJSONEncoder{},
+		"application/protobuf+json": protoJSONEncoder{},
+	}
+)
+
+// RegisterEncoder makes e available to clients requesting mediaType in their Accept header,
+// replacing any encoder previously registered for it.
+// Vendor media types with a "+suffix", e.g. "application/vnd.novometrix.v2+cbor", use the encoder registered for
+// "application/suffix".
+func RegisterEncoder(mediaType string, e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[strings.ToLower(mediaType)] = e
+}
+
+// lookupEncoder returns the encoder registered for mediaType. The wildcard media ranges match the JSON default.
+func lookupEncoder(mediaType string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	if _, suffix, ok := splitVendorMediaType(mediaType); ok {
+		mediaType = "application/" + suffix
+	}
+	if mediaType == "*/*" || mediaType == "application/*" {
+		mediaType = MediaTypeJSON
+	}
+
+	e, ok := encoders[mediaType]
+	return e, ok
+}
+
+// getEncoder negotiates the encoder of the response from the Accept header, picking the supported media type with
+// the highest quality. Defaults to JSON.
+func getEncoder(c *gin.Context) Encoder {
+	if e, exists := c.Get(encoderContextKey); exists {
+		return e.(Encoder)
+	}
+
+	var e Encoder = jsonEncoder{}
+	for _, mr := range acceptedMediaTypes(c.GetHeader("Accept")) {
+		if found, ok := lookupEncoder(mr.mediaType); ok {
+			e = found
+			break
+		}
+	}
+
+	c.Set(encoderContextKey, e)
+	return e
+}
+
+// isJSONEncoder reports whether e writes plain JSON, so that JSON payloads can be spliced in as is.
+func isJSONEncoder(e Encoder) bool {
+	_, ok := e.(jsonEncoder)
+	return ok
+}
+
+type mediaRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// acceptedMediaTypes parses an Accept header, skipping invalid media ranges and the ones with q=0. The media ranges
+// are sorted by decreasing quality, ties keeping the order of the header.
+func acceptedMediaTypes(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, r := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(r)
+		if err != nil {
+			continue
+		}
+		q, err := strconv.ParseFloat(params["q"], 64)
+		if err != nil {
+			q = 1
+		}
+		if q <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, params: params, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	return ranges
+}
+
+// splitVendorMediaType splits e.g. "application/vnd.novometrix.v2+json" into "v2" and "json".
+func splitVendorMediaType(mediaType string) (version, suffix string, ok bool) {
+	if !strings.HasPrefix(mediaType, vendorMediaTypePrefix) {
+		return "", "", false
+	}
+
+	return strings.Cut(strings.TrimPrefix(mediaType, vendorMediaTypePrefix), "+")
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (jsonEncoder) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// protoJSONEncoder writes JSON with the lowerCamelCase envelope field names of the proto3 JSON mapping. The payload is
+// written as plain JSON, its keys and values untouched, e.g. 64-bit integers are not written as strings.
+type protoJSONEncoder struct{}
+
+func (protoJSONEncoder) ContentType() string {
+	return MediaTypeProtoJSON + "; charset=utf-8"
+}
+
+func (protoJSONEncoder) Encode(v any) ([]byte, error) {
+	t, err := toValueTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := v.(envelopeValue); ok {
+		t = renameEnvelopeKeys(t)
+	}
+
+	var buf bytes.Buffer
+	err = writeValueTreeJSON(&buf, t)
+	return buf.Bytes(), err
+}
+
+// renameEnvelopeKeys converts the keys of an envelope value tree to lowerCamelCase, leaving the payload untouched.
+func renameEnvelopeKeys(v any) any {
+	o, ok := v.(object)
+	if !ok {
+		return v
+	}
+
+	renamed := make(object, len(o))
+	for i, m := range o {
+		renamed[i] = member{key: lowerCamelCase(m.key), value: m.value}
+		switch m.key {
+		case "payload", "data", "details":
+		default:
+			renamed[i].value = renameEnvelopeKeys(m.value)
+		}
+	}
+
+	return renamed
+}
+
+// writeValueTreeJSON writes a value tree as JSON.
+func writeValueTreeJSON(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case object:
+		buf.WriteByte('{')
+		for i, m := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(m.key)
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err := writeValueTreeJSON(buf, m.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeValueTreeJSON(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+
+	return nil
+}
+
+func lowerCamelCase(s string) string {
+	var sb strings.Builder
+	upper := false
+	for i, r := range s {
+		switch {
+		case r == '_':
+			upper = i > 0
+		case upper:
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		case sb.Len() == 0:
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// object is a JSON object keeping the order of its members.
+type object []member
+
+type member struct {
+	key   string
+	value any
+}
+
+var errInvalidJSON = errors.New("invalid JSON value")
+
+// toValueTree converts v to its JSON representation made of object, []any, string, bool, nil, int64, uint64 and
+// float64 values, so that binary encoders don't have to deal with struct tags and json.Marshaler implementations.
+func toValueTree(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValueTree(b)
+}
+
+func parseValueTree(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	v, err := readValue(d)
+	if err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errInvalidJSON
+	}
+
+	return v, nil
+}
+
+func readValue(d *json.Decoder) (any, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			o := object{}
+			for d.More() {
+				k, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := readValue(d)
+				if err != nil {
+					return nil, err
+				}
+				o = append(o, member{key: k.(string), value: v})
+			}
+			_, err = d.Token()
+			return o, err
+		case '[':
+			a := []any{}
+			for d.More() {
+				v, err := readValue(d)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+			_, err = d.Token()
+			return a, err
+		default:
+			return nil, errInvalidJSON
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(t.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return t.Float64()
+	default:
+		return t, nil
+	}
+}
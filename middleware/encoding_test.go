@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		msgpack   string
+		cbor      string
+		protoJSON string
+	}{
+		{
+			name:      "success_object",
+			input:     `{"b_key":[true,null,"x"],"a":1}`,
+			msgpack:   "82a5625f6b657993c3c0a178a16101",
+			cbor:      "a265625f6b657983f5f66178616101",
+			protoJSON: `{"b_key":[true,null,"x"],"a":1}`,
+		},
+		{
+			name:      "success_numbers",
+			input:     `[-1,-200,300,1.5,18446744073709551615]`,
+			msgpack:   "95ffd1ff38cd012ccb3ff8000000000000cfffffffffffffffff",
+			cbor:      "852038c719012cfb3ff80000000000001bffffffffffffffff",
+			protoJSON: `[-1,-200,300,1.5,18446744073709551615]`,
+		},
+		{
+			name:      "success_long_string",
+			input:     `"` + strings.Repeat("a", 32) + `"`,
+			msgpack:   "d920" + strings.Repeat("61", 32),
+			cbor:      "7820" + strings.Repeat("61", 32),
+			protoJSON: `"` + strings.Repeat("a", 32) + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			m, err := msgpackEncoder{}.Encode(json.RawMessage(tt.input))
+			a.NoError(err)
+			a.Equal(tt.msgpack, hex.EncodeToString(m))
+
+			c, err := cborEncoder{}.Encode(json.RawMessage(tt.input))
+			a.NoError(err)
+			a.Equal(tt.cbor, hex.EncodeToString(c))
+
+			p, err := protoJSONEncoder{}.Encode(json.RawMessage(tt.input))
+			a.NoError(err)
+			a.Equal(tt.protoJSON, string(p))
+		})
+	}
+}
+
+func TestProtoJSONEncoder(t *testing.T) {
+	payload := json.RawMessage(`{"user_id":1,"next_cursor":"abc"}`)
+	resp := BaseResponse[json.RawMessage]{
+		Status:     "OK",
+		StatusCode: 200,
+		Payload:    payload,
+		Meta:       &Meta{PageSize: 10, NextCursor: "abc"},
+	}
+	notFound := BaseResponse[json.RawMessage]{Status: "Not Found", StatusCode: 404, Payload: payload}
+
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+	}{
+		{
+			name:     "success_v1",
+			input:    envelope(EnvelopeV1, resp),
+			expected: `{"status":"OK","statusCode":200,"requestId":"","payload":{"user_id":1,"next_cursor":"abc"},"meta":{"pageSize":10,"nextCursor":"abc"}}`,
+		},
+		{
+			name:     "success_v2_error",
+			input:    envelope(EnvelopeV2, notFound),
+			expected: `{"error":{"message":"Not Found","details":{"user_id":1,"next_cursor":"abc"}},"meta":{"status":"Not Found","statusCode":404,"requestId":""}}`,
+		},
+		{
+			name:     "success_no_envelope",
+			input:    envelope(EnvelopeNone, resp),
+			expected: `{"user_id":1,"next_cursor":"abc"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			b, err := protoJSONEncoder{}.Encode(tt.input)
+			a.NoError(err)
+			a.Equal(tt.expected, string(b))
+		})
+	}
+}
+
+type upperEncoder struct{}
+
+func (upperEncoder) ContentType() string {
+	return "text/x-upper"
+}
+
+func (upperEncoder) Encode(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return []byte(strings.ToUpper(string(b))), err
+}
+
+func TestEncoderNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	RegisterEncoder("text/x-upper", upperEncoder{})
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		binary      bool
+		expected    string
+	}{
+		{
+			name:        "success_default_json",
+			accept:      "*/*",
+			contentType: "application/json; charset=utf-8",
+			expected:    `{"status":"OK","status_code":200,"request_id":"","payload":{"id":1}}`,
+		},
+		{
+			name:        "success_wildcard_quality",
+			accept:      "*/*, application/cbor;q=0.1",
+			contentType: "application/json; charset=utf-8",
+			expected:    `{"status":"OK","status_code":200,"request_id":"","payload":{"id":1}}`,
+		},
+		{
+			name:        "success_application_wildcard_quality",
+			accept:      "application/cbor;q=0.5, application/*",
+			contentType: "application/json; charset=utf-8",
+			expected:    `{"status":"OK","status_code":200,"request_id":"","payload":{"id":1}}`,
+		},
+		{
+			name:        "success_msgpack",
+			accept:      "text/html, application/msgpack",
+			contentType: MediaTypeMsgpack,
+			binary:      true,
+			expected:    hex.EncodeToString([]byte{0x84, 0xa6}) + hex.EncodeToString([]byte("status")),
+		},
+		{
+			name:        "success_msgpack_quality",
+			accept:      "application/json;q=0.1, application/msgpack",
+			contentType: MediaTypeMsgpack,
+			binary:      true,
+			expected:    hex.EncodeToString([]byte{0x84, 0xa6}) + hex.EncodeToString([]byte("status")),
+		},
+		{
+			name:        "success_vendor_cbor_raw",
+			accept:      "application/vnd.novometrix.raw+cbor",
+			contentType: MediaTypeCBOR,
+			binary:      true,
+			expected:    "a1626964" + "01",
+		},
+		{
+			name:        "success_protojson_v2",
+			accept:      "application/x-protobuf-json; envelope=v2",
+			contentType: "application/x-protobuf-json; charset=utf-8",
+			expected:    `{"data":{"id":1},"meta":{"status":"OK","statusCode":200,"requestId":""}}`,
+		},
+		{
+			name:        "success_registered",
+			accept:      "text/x-upper",
+			contentType: "text/x-upper",
+			expected:    `{"STATUS":"OK","STATUS_CODE":200,"REQUEST_ID":"","PAYLOAD":{"ID":1}}`,
+		},
+	}
+
+	handlers := map[string]gin.HandlerFunc{
+		"wrapper": func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"id": 1})
+		},
+		"helper": func(c *gin.Context) {
+			OK(c, gin.H{"id": 1})
+		},
+	}
+
+	for _, tt := range tests {
+		for name, handler := range handlers {
+			t.Run(tt.name+"_"+name, func(t *testing.T) {
+				a := assert.New(t)
+
+				w := httptest.NewRecorder()
+				_, e := gin.CreateTestContext(w)
+
+				e.Use(ResponseWrapperMiddleware())
+				e.GET("/", handler)
+
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept", tt.accept)
+				e.ServeHTTP(w, req)
+
+				a.Equal(http.StatusOK, w.Code)
+				a.Equal(tt.contentType, w.Header().Get("Content-Type"))
+
+				body := w.Body.String()
+				if tt.binary {
+					body = hex.EncodeToString(w.Body.Bytes())
+				}
+				a.True(strings.HasPrefix(body, tt.expected), body)
+			})
+		}
+	}
+}
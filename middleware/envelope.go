@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+type EnvelopeVersion int
+
+const (
+	// EnvelopeNone writes the bare payload, without any envelope.
+	EnvelopeNone EnvelopeVersion = iota
+	// EnvelopeV1 writes the payload in a BaseResponse.
+	EnvelopeV1
+	// EnvelopeV2 writes the payload in a BaseResponseV2.
+	EnvelopeV2
+)
+
+const (
+	// EnvelopeHeader selects the envelope version with one of "none", "v1" or "v2".
+	EnvelopeHeader = "X-Envelope-Version"
+
+	// EnvelopeMediaTypeParam selects the envelope version through a parameter of a media type in the Accept header,
+	// e.g. "application/json; envelope=v2".
+	EnvelopeMediaTypeParam = "envelope"
+
+	// vendorMediaTypePrefix selects the envelope version through a vendor media type in the Accept header,
+	// e.g. "application/vnd.novometrix.v2+json". "application/vnd.novometrix.raw+json" selects EnvelopeNone.
+	vendorMediaTypePrefix = "application/vnd.novometrix."
+
+	envelopeVersionContextKey = "github.com/Novometrix/util/middleware.envelopeVersion"
+)
+
+type EnvelopeMeta struct {
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code"`
+	RequestID  string `json:"request_id"`
+	*Meta
+}
+
+type EnvelopeError struct {
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// BaseResponseV2 is the version 2 envelope.
+// Unlike BaseResponse, the payload of error responses is written in the error section instead of data.
+type BaseResponseV2[T any] struct {
+	Data  *T             `json:"data,omitempty"`
+	Error *EnvelopeError `json:"error,omitempty"`
+	Meta  EnvelopeMeta   `json:"meta"`
+	Links *Links         `json:"links,omitempty"`
+}
+
+// V2 converts the response to the version 2 envelope.
+func (r BaseResponse[T]) V2() BaseResponseV2[T] {
+	resp := BaseResponseV2[T]{
+		Meta: EnvelopeMeta{
+			Status:     r.Status,
+			StatusCode: r.StatusCode,
+			RequestID:  r.RequestID,
+			Meta:       r.Meta,
+		},
+		Links: r.Links,
+	}
+
+	if r.StatusCode >= 400 {
+		resp.Error = &EnvelopeError{
+			Message: r.Status,
+			Details: r.Payload,
+		}
+	} else {
+		resp.Data = &r.Payload
+	}
+
+	return resp
+}
+
+// envelopeValue is implemented by the envelopes, so that encoders can tell their fields from the payload.
+type envelopeValue interface {
+	isEnvelope()
+}
+
+func (BaseResponse[T]) isEnvelope() {}
+
+func (BaseResponseV2[T]) isEnvelope() {}
+
+// envelope returns the value to be serialized for resp in envelope version v.
+func envelope[T any](v EnvelopeVersion, resp BaseResponse[T]) any {
+	switch v {
+	case EnvelopeNone:
+		return resp.Payload
+	case EnvelopeV2:
+		return resp.V2()
+	default:
+		return resp
+	}
+}
+
+// getEnvelopeVersion negotiates the envelope version of the response from the request headers.
+// The X-Envelope-Version header takes precedence over the Accept header. Defaults to EnvelopeV1.
+func getEnvelopeVersion(c *gin.Context) EnvelopeVersion {
+	if v, exists := c.Get(envelopeVersionContextKey); exists {
+		return v.(EnvelopeVersion)
+	}
+
+	v, ok := parseEnvelopeVersion(c.GetHeader(EnvelopeHeader))
+	if !ok {
+		v, ok = negotiateEnvelopeVersion(c.GetHeader("Accept"))
+	}
+	if !ok {
+		v = EnvelopeV1
+	}
+
+	c.Set(envelopeVersionContextKey, v)
+	return v
+}
+
+// negotiateEnvelopeVersion returns the envelope version selected by the supported media range of accept with the
+// highest quality, ties going to the first one. Media ranges without an envelope version, e.g. "*/*", select the
+// default one.
+func negotiateEnvelopeVersion(accept string) (EnvelopeVersion, bool) {
+	for _, mr := range acceptedMediaTypes(accept) {
+		if version, _, ok := splitVendorMediaType(mr.mediaType); ok {
+			if version == "raw" {
+				return EnvelopeNone, true
+			}
+			if v, ok := parseEnvelopeVersion(version); ok {
+				return v, true
+			}
+			continue
+		}
+
+		if _, ok := lookupEncoder(mr.mediaType); ok {
+			return parseEnvelopeVersion(mr.params[EnvelopeMediaTypeParam])
+		}
+	}
+
+	return 0, false
+}
+
+func parseEnvelopeVersion(s string) (EnvelopeVersion, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none":
+		return EnvelopeNone, true
+	case "v1", "1":
+		return EnvelopeV1, true
+	case "v2", "2":
+		return EnvelopeV2, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnvelopeNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type InputStruct struct {
+		Name string `json:"name"`
+	}
+
+	v1 := `{"status":"OK","status_code":200,"request_id":"` + TestID + `","payload":{"name":"hi"}}`
+	v2 := `{"data":{"name":"hi"},"meta":{"status":"OK","status_code":200,"request_id":"` + TestID + `"}}`
+	v2Error := `{"error":{"message":"Not Found","details":{"name":"hi"}},"meta":{"status":"Not Found","status_code":404,"request_id":"` + TestID + `"}}`
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		status   int
+		expected string
+	}{
+		{
+			name:     "success_default_v1",
+			status:   http.StatusOK,
+			expected: v1,
+		},
+		{
+			name:     "success_accept_vendor_v2",
+			headers:  map[string]string{"Accept": "text/html, application/vnd.novometrix.v2+json"},
+			status:   http.StatusOK,
+			expected: v2,
+		},
+		{
+			name:     "success_accept_vendor_raw",
+			headers:  map[string]string{"Accept": "application/vnd.novometrix.raw+json"},
+			status:   http.StatusOK,
+			expected: `{"name":"hi"}`,
+		},
+		{
+			name:     "success_accept_param_v2",
+			headers:  map[string]string{"Accept": "application/json; envelope=v2"},
+			status:   http.StatusOK,
+			expected: v2,
+		},
+		{
+			name:     "success_accept_q_zero_ignored",
+			headers:  map[string]string{"Accept": "application/vnd.novometrix.v2+json;q=0, application/json"},
+			status:   http.StatusOK,
+			expected: v1,
+		},
+		{
+			name:     "success_accept_quality",
+			headers:  map[string]string{"Accept": "application/vnd.novometrix.v1+json;q=0.5, application/vnd.novometrix.v2+json"},
+			status:   http.StatusOK,
+			expected: v2,
+		},
+		{
+			name:     "success_wildcard_quality",
+			headers:  map[string]string{"Accept": "*/*, application/vnd.novometrix.v2+json;q=0.1"},
+			status:   http.StatusOK,
+			expected: v1,
+		},
+		{
+			name:     "success_wildcard_envelope",
+			headers:  map[string]string{"Accept": "*/*; envelope=v2"},
+			status:   http.StatusOK,
+			expected: v2,
+		},
+		{
+			name:     "success_header_precedence",
+			headers:  map[string]string{"Accept": "application/vnd.novometrix.v2+json", EnvelopeHeader: "v1"},
+			status:   http.StatusOK,
+			expected: v1,
+		},
+		{
+			name:     "success_header_none",
+			headers:  map[string]string{EnvelopeHeader: "none"},
+			status:   http.StatusOK,
+			expected: `{"name":"hi"}`,
+		},
+		{
+			name:     "success_v2_error",
+			headers:  map[string]string{EnvelopeHeader: "v2"},
+			status:   http.StatusNotFound,
+			expected: v2Error,
+		},
+	}
+
+	handlers := map[string]gin.HandlerFunc{
+		"wrapper": func(c *gin.Context) {
+			c.JSON(c.GetInt("status"), InputStruct{Name: "hi"})
+		},
+		"helper": func(c *gin.Context) {
+			Respond(c, c.GetInt("status"), InputStruct{Name: "hi"})
+		},
+	}
+
+	for _, tt := range tests {
+		for name, handler := range handlers {
+			t.Run(tt.name+"_"+name, func(t *testing.T) {
+				a := assert.New(t)
+
+				w := httptest.NewRecorder()
+				_, e := gin.CreateTestContext(w)
+
+				e.Use(ResponseWrapperMiddleware())
+				e.GET("/", func(c *gin.Context) {
+					c.Set("status", tt.status)
+					c.Header("Vary", "Origin")
+				}, handler)
+
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set(RequestIDHeader, TestID)
+				for k, v := range tt.headers {
+					req.Header.Set(k, v)
+				}
+				e.ServeHTTP(w, req)
+
+				a.Equal(tt.status, w.Code)
+				a.JSONEq(tt.expected, w.Body.String())
+				vary := strings.Join(w.Header().Values("Vary"), ", ")
+				a.Contains(vary, "Origin")
+				a.Contains(vary, EnvelopeHeader)
+			})
+		}
+	}
+}
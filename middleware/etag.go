@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const etagWriterContextKey = "github.com/Novometrix/util/middleware.etagWriter"
+
+// ETagMiddleware adds a strong ETag to successful GET and HEAD responses and answers conditional requests.
+// Unless the handler provided one with SetETag, the ETag is a hash of the response body. The request ID differing
+// between requests for the same representation, wrapped responses are hashed as encoded without it.
+// Requests with a matching If-None-Match, or If-Modified-Since when the handler set the Last-Modified header, get a
+// 304 Not Modified response.
+// The middleware must be registered before ResponseWrapperMiddleware, so that the wrapped response is hashed.
+func ETagMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		ew := &etagWriter{
+			ResponseWriter: c.Writer,
+		}
+		c.Writer = ew
+		c.Set(etagWriterContextKey, ew)
+
+		c.Next()
+
+		c.Writer = ew.ResponseWriter
+		if ew.passthrough {
+			return
+		}
+
+		h := ew.Header()
+		if ew.Status() == http.StatusOK {
+			etag := h.Get("ETag")
+			if etag == "" {
+				body := ew.buf.Bytes()
+				if ew.representation != nil {
+					body = ew.representation
+				}
+				sum := sha256.Sum256(body)
+				etag = `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+				h.Set("ETag", etag)
+			}
+
+			if isNotModified(c.Request, etag, h.Get("Last-Modified")) {
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				ew.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		if ew.buf.Len() > 0 {
+			_, _ = ew.ResponseWriter.Write(ew.buf.Bytes())
+		}
+	}
+}
+
+// SetETag sets the ETag of the response to version, e.g. the revision of the resource being returned.
+func SetETag(c *gin.Context, version string) {
+	c.Header("ETag", formatETag(version))
+}
+
+// SetLastModified sets the Last-Modified header of the response, enabling If-Modified-Since requests.
+func SetLastModified(c *gin.Context, t time.Time) {
+	c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// CheckIfMatch compares the If-Match header of the request with version, the current version of the resource
+// to be modified, for optimistic concurrency control of PUT and PATCH requests.
+// If they do not match, the request is aborted with 412 Precondition Failed and false is returned.
+// Requests without an If-Match header always match.
+func CheckIfMatch(c *gin.Context, version string) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || matchETag(ifMatch, formatETag(version), false) {
+		return true
+	}
+
+	Fail(c, http.StatusPreconditionFailed, errorPayload{Error: http.StatusText(http.StatusPreconditionFailed)})
+	return false
+}
+
+func isNotModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchETag(inm, etag, true)
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !lm.After(ims)
+}
+
+// matchETag reports whether etag is in the comma-separated list of a If-Match or If-None-Match header.
+// Weak comparison is used for If-None-Match, strong comparison for If-Match, see RFC 9110 section 8.8.3.2.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if !weak && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatETag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+
+	return `"` + version + `"`
+}
+
+type etagWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	passthrough bool
+	// representation, if set, is hashed instead of the body.
+	representation []byte
+}
+
+// setETagRepresentation gives ETagMiddleware, if in use, the response to hash in place of the body, encoded by encode
+// without its request ID.
+func setETagRepresentation(c *gin.Context, encode func() ([]byte, error)) {
+	ew, exists := c.Get(etagWriterContextKey)
+	if !exists {
+		return
+	}
+
+	b, err := encode()
+	if err != nil {
+		log.Errorf("failed to encode ETag representation with error: %v", err)
+		return
+	}
+
+	ew.(*etagWriter).representation = b
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(b)
+	}
+
+	return ew.buf.Write(b)
+}
+
+func (ew *etagWriter) WriteString(s string) (int, error) {
+	return ew.Write([]byte(s))
+}
+
+// Flush gives up on the ETag of streamed responses, writing the buffered body as is.
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		ew.passthrough = true
+		if ew.buf.Len() > 0 {
+			_, _ = ew.ResponseWriter.Write(ew.buf.Bytes())
+			ew.buf.Reset()
+		}
+	}
+
+	ew.ResponseWriter.Flush()
+}
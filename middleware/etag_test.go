@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lastModified := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	serve := func(handler gin.HandlerFunc, requestID string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		_, e := gin.CreateTestContext(w)
+
+		e.Use(ETagMiddleware(), ResponseWrapperMiddleware())
+		e.GET("/", handler)
+
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, requestID)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		e.ServeHTTP(w, req)
+
+		return w
+	}
+
+	payload := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	}
+
+	tests := []struct {
+		name     string
+		handler  gin.HandlerFunc
+		headers  func(etag string) map[string]string
+		expected int
+	}{
+		{
+			name:    "success_no_condition",
+			handler: payload,
+			headers: func(etag string) map[string]string {
+				return nil
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name:    "success_if_none_match",
+			handler: payload,
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-None-Match": `"other", W/` + etag}
+			},
+			expected: http.StatusNotModified,
+		},
+		{
+			name:    "success_if_none_match_mismatch",
+			handler: payload,
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-None-Match": `"other"`}
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name: "success_handler_etag",
+			handler: func(c *gin.Context) {
+				SetETag(c, "v42")
+				payload(c)
+			},
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-None-Match": `"v42"`}
+			},
+			expected: http.StatusNotModified,
+		},
+		{
+			name: "success_if_modified_since",
+			handler: func(c *gin.Context) {
+				SetLastModified(c, lastModified)
+				payload(c)
+			},
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)}
+			},
+			expected: http.StatusNotModified,
+		},
+		{
+			name: "success_if_modified_since_modified",
+			handler: func(c *gin.Context) {
+				SetLastModified(c, lastModified)
+				payload(c)
+			},
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name: "success_error_response_not_cached",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusNotFound, gin.H{})
+			},
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-None-Match": "*"}
+			},
+			expected: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			first := serve(tt.handler, TestID, nil)
+			etag := first.Header().Get("ETag")
+
+			w := serve(tt.handler, "another-request-id", tt.headers(etag))
+
+			a.Equal(tt.expected, w.Code)
+			if tt.expected == http.StatusNotFound {
+				a.Empty(etag)
+				return
+			}
+
+			a.NotEmpty(etag)
+			a.Equal(etag, w.Header().Get("ETag"))
+			if tt.expected == http.StatusNotModified {
+				a.Empty(w.Body.String())
+			} else {
+				a.Contains(w.Body.String(), "another-request-id")
+			}
+		})
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		ifMatch  string
+		expected int
+	}{
+		{name: "success_no_header", ifMatch: "", expected: http.StatusOK},
+		{name: "success_match", ifMatch: `"v1", "v2"`, expected: http.StatusOK},
+		{name: "success_wildcard", ifMatch: "*", expected: http.StatusOK},
+		{name: "error_mismatch", ifMatch: `"v1"`, expected: http.StatusPreconditionFailed},
+		{name: "error_weak", ifMatch: `W/"v2"`, expected: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.PUT("/", func(c *gin.Context) {
+				if !CheckIfMatch(c, "v2") {
+					return
+				}
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodPut, "/", nil)
+			req.Header.Set("If-Match", tt.ifMatch)
+			e.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestETagMiddleware_ShortRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handlers := map[string]func(count int) gin.HandlerFunc{
+		"wrapper": func(count int) gin.HandlerFunc {
+			return func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"count": count})
+			}
+		},
+		"helper": func(count int) gin.HandlerFunc {
+			return func(c *gin.Context) {
+				OK(c, gin.H{"count": count})
+			}
+		},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+
+			serve := func(count int, ifNoneMatch string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				_, e := gin.CreateTestContext(w)
+
+				e.Use(ETagMiddleware(), ResponseWrapperMiddleware())
+				e.GET("/", handler(count))
+
+				req, _ := http.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set(RequestIDHeader, "1")
+				req.Header.Set("If-None-Match", ifNoneMatch)
+				e.ServeHTTP(w, req)
+
+				return w
+			}
+
+			etag := serve(1, "").Header().Get("ETag")
+			a.NotEmpty(etag)
+
+			w := serve(11, etag)
+			a.Equal(http.StatusOK, w.Code)
+			a.NotEqual(etag, w.Header().Get("ETag"))
+			a.Contains(w.Body.String(), `"count":11`)
+
+			a.Equal(http.StatusNotModified, serve(1, etag).Code)
+		})
+	}
+}
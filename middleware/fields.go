@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const (
+	FieldsQueryParam = "fields"
+
+	fieldSetContextKey = "github.com/Novometrix/util/middleware.fieldSet"
+)
+
+// fieldSet is a tree of selected fields. A nil fieldSet selects the whole value.
+type fieldSet map[string]fieldSet
+
+// FieldsMiddleware enables sparse fieldsets on a route: the payload of successful responses is pruned to the
+// comma-separated fields of the fields query parameter, e.g. "?fields=id,name,owner.email". Fields of arrays apply to each of their elements.
+// If allowed is not empty, requesting a field that is neither allowed nor nested in an allowed field aborts the
+// request with 400 Bad Request.
+func FieldsMiddleware(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields := c.Query(FieldsQueryParam)
+		if fields == "" {
+			c.Next()
+			return
+		}
+
+		fs := fieldSet{}
+		for _, f := range strings.Split(fields, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			if len(allowed) > 0 && !isAllowedField(f, allowed) {
+				Fail(c, http.StatusBadRequest, errorPayload{Error: fmt.Sprintf("field %q is not allowed", f)})
+				return
+			}
+			fs.add(strings.Split(f, "."))
+		}
+
+		c.Set(fieldSetContextKey, fs)
+		c.Next()
+	}
+}
+
+func isAllowedField(field string, allowed []string) bool {
+	for _, a := range allowed {
+		if field == a || strings.HasPrefix(field, a+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (fs fieldSet) add(path []string) {
+	child, exists := fs[path[0]]
+	if len(path) == 1 {
+		fs[path[0]] = nil
+		return
+	}
+	if exists && child == nil {
+		// The whole value is already selected.
+		return
+	}
+	if child == nil {
+		child = fieldSet{}
+		fs[path[0]] = child
+	}
+
+	child.add(path[1:])
+}
+
+// isPrunedStatus reports whether the payloads of responses with status are pruned. Only successful payloads are, so
+// error payloads keep their message.
+func isPrunedStatus(status int) bool {
+	return status >= http.StatusOK && status < http.StatusMultipleChoices
+}
+
+func getFieldSet(c *gin.Context) (fieldSet, bool) {
+	fs, exists := c.Get(fieldSetContextKey)
+	if !exists {
+		return nil, false
+	}
+
+	return fs.(fieldSet), true
+}
+
+// pruneJSON returns the JSON document b without the fields not in fs.
+func (fs fieldSet) pruneJSON(b []byte) (json.RawMessage, error) {
+	t, err := parseValueTree(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = writeValueTreeJSON(&buf, fs.prune(t))
+	return buf.Bytes(), err
+}
+
+func (fs fieldSet) prune(v any) any {
+	if fs == nil {
+		return v
+	}
+
+	switch v := v.(type) {
+	case object:
+		pruned := object{}
+		for _, m := range v {
+			if child, selected := fs[m.key]; selected {
+				pruned = append(pruned, member{key: m.key, value: child.prune(m.value)})
+			}
+		}
+		return pruned
+	case []any:
+		pruned := make([]any, len(v))
+		for i, e := range v {
+			pruned[i] = fs.prune(e)
+		}
+		return pruned
+	default:
+		return v
+	}
+}
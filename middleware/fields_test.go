@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFieldsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type Owner struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	}
+	type Item struct {
+		ID    int64  `json:"id"`
+		Name  string `json:"name"`
+		Owner Owner  `json:"owner"`
+	}
+
+	item := Item{ID: 1 << 60, Name: "item", Owner: Owner{Email: "owner@example.com", Name: "owner"}}
+
+	tests := []struct {
+		name     string
+		query    string
+		allowed  []string
+		status   int
+		expected string
+	}{
+		{
+			name:     "success_no_fields",
+			query:    "",
+			status:   http.StatusOK,
+			expected: `{"id":1152921504606846976,"name":"item","owner":{"email":"owner@example.com","name":"owner"}}`,
+		},
+		{
+			name:     "success_top_level",
+			query:    "?fields=name,id",
+			status:   http.StatusOK,
+			expected: `{"id":1152921504606846976,"name":"item"}`,
+		},
+		{
+			name:     "success_nested",
+			query:    "?fields=id,owner.email",
+			allowed:  []string{"id", "owner"},
+			status:   http.StatusOK,
+			expected: `{"id":1152921504606846976,"owner":{"email":"owner@example.com"}}`,
+		},
+		{
+			name:     "success_whole_and_nested",
+			query:    "?fields=owner.email,owner",
+			status:   http.StatusOK,
+			expected: `{"owner":{"email":"owner@example.com","name":"owner"}}`,
+		},
+		{
+			name:    "error_not_allowed",
+			query:   "?fields=id,owner.email",
+			allowed: []string{"id", "owner.name"},
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	handlers := map[string]gin.HandlerFunc{
+		"wrapper": func(c *gin.Context) {
+			c.JSON(http.StatusOK, []Item{item})
+		},
+		"helper": func(c *gin.Context) {
+			OK(c, []Item{item})
+		},
+	}
+
+	for _, tt := range tests {
+		for name, handler := range handlers {
+			t.Run(tt.name+"_"+name, func(t *testing.T) {
+				a := assert.New(t)
+
+				w := httptest.NewRecorder()
+				_, e := gin.CreateTestContext(w)
+
+				e.Use(ResponseWrapperMiddleware())
+				e.GET("/", FieldsMiddleware(tt.allowed...), handler)
+
+				req, _ := http.NewRequest(http.MethodGet, "/"+tt.query, nil)
+				req.Header.Set(EnvelopeHeader, "none")
+				e.ServeHTTP(w, req)
+
+				a.Equal(tt.status, w.Code)
+				if tt.status == http.StatusOK {
+					a.Equal("["+tt.expected+"]", w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestFieldsMiddleware_ErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handlers := map[string]gin.HandlerFunc{
+		"wrapper": func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		},
+		"helper": func(c *gin.Context) {
+			Fail(c, http.StatusUnauthorized, errorPayload{Error: "Unauthorized"})
+		},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(ResponseWrapperMiddleware())
+			e.GET("/", FieldsMiddleware(), handler)
+
+			req, _ := http.NewRequest(http.MethodGet, "/?fields=id", nil)
+			req.Header.Set(EnvelopeHeader, "none")
+			e.ServeHTTP(w, req)
+
+			a.Equal(http.StatusUnauthorized, w.Code)
+			a.JSONEq(`{"error":"Unauthorized"}`, w.Body.String())
+		})
+	}
+}
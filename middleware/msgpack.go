@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// msgpackEncoder writes MessagePack, see https://github.com/msgpack/msgpack/blob/master/spec.md.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string {
+	return MediaTypeMsgpack
+}
+
+func (msgpackEncoder) Encode(v any) ([]byte, error) {
+	t, err := toValueTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = writeMsgpack(&buf, t)
+	return buf.Bytes(), err
+}
+
+func writeMsgpack(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int64:
+		if v >= 0 {
+			writeMsgpackUint(buf, uint64(v))
+		} else {
+			writeMsgpackInt(buf, v)
+		}
+	case uint64:
+		writeMsgpackUint(buf, v)
+	case float64:
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []any:
+		writeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, e := range v {
+			if err := writeMsgpack(buf, e); err != nil {
+				return err
+			}
+		}
+	case object:
+		writeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, m := range v {
+			_ = writeMsgpack(buf, m.key)
+			if err := writeMsgpack(buf, m.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+
+	return nil
+}
+
+func writeMsgpackUint(buf *bytes.Buffer, v uint64) {
+	switch {
+	case v < 1<<7:
+		buf.WriteByte(byte(v))
+	case v <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(v)})
+	case v <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		_ = binary.Write(buf, binary.BigEndian, uint16(v))
+	case v <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		_ = binary.Write(buf, binary.BigEndian, uint32(v))
+	default:
+		buf.WriteByte(0xcf)
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, v int64) {
+	switch {
+	case v >= -32:
+		buf.WriteByte(byte(v))
+	case v >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(v)})
+	case v >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		_ = binary.Write(buf, binary.BigEndian, int16(v))
+	case v >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(v))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+}
+
+// writeMsgpackHeader writes the header of a string, array or map of length n.
+// Lengths below fixMax are written in the fix prefix, the 8-bit prefix is skipped if it is 0.
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, p8, p16, p32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case p8 != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{p8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(p16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(p32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
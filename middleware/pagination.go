@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	PageQueryParam     = "page"
+	PageSizeQueryParam = "page_size"
+	CursorQueryParam   = "cursor"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+)
+
+type Meta struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type Links struct {
+	Self  string `json:"self,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// Paginator describes a page of results, see OffsetPage and CursorPage.
+type Paginator interface {
+	Meta() *Meta
+	// Links returns the pagination links relative to the request URL u.
+	Links(u *url.URL) *Links
+}
+
+// OffsetPage is a 1-indexed page of an offset-paginated list.
+type OffsetPage struct {
+	Page     int
+	PageSize int
+	Total    int64
+}
+
+// ParseOffsetPage reads the page and page size from the query parameters of the request.
+// Missing or invalid values fall back to the first page and defaultPageSize, and the page size is capped at maxPageSize.
+func ParseOffsetPage(c *gin.Context, defaultPageSize, maxPageSize int) OffsetPage {
+	page, err := strconv.Atoi(c.Query(PageQueryParam))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	return OffsetPage{
+		Page:     page,
+		PageSize: parsePageSize(c, defaultPageSize, maxPageSize),
+	}
+}
+
+// Offset returns the number of items preceding the page.
+func (p OffsetPage) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// LastPage returns the number of the last page, which is at least 1.
+func (p OffsetPage) LastPage() int {
+	if p.PageSize < 1 || p.Total < 1 {
+		return 1
+	}
+
+	return int((p.Total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+func (p OffsetPage) Meta() *Meta {
+	total := p.Total
+
+	return &Meta{
+		Page:     p.Page,
+		PageSize: p.PageSize,
+		Total:    &total,
+	}
+}
+
+func (p OffsetPage) Links(u *url.URL) *Links {
+	link := func(page int) string {
+		return withQuery(u, map[string]string{
+			PageQueryParam:     strconv.Itoa(page),
+			PageSizeQueryParam: strconv.Itoa(p.PageSize),
+		})
+	}
+
+	last := p.LastPage()
+	links := &Links{
+		Self:  link(p.Page),
+		First: link(1),
+		Last:  link(last),
+	}
+	if p.Page < last {
+		links.Next = link(p.Page + 1)
+	}
+	if p.Page > 1 {
+		links.Prev = link(p.Page - 1)
+	}
+
+	return links
+}
+
+// CursorPage is a page of a cursor-paginated list.
+// Cursors are opaque to clients, see CursorCodec for encoding them.
+type CursorPage struct {
+	Cursor     string
+	NextCursor string
+	PrevCursor string
+	PageSize   int
+}
+
+// ParseCursorPage reads the cursor and page size from the query parameters of the request.
+// A missing or invalid page size falls back to defaultPageSize, and is capped at maxPageSize.
+func ParseCursorPage(c *gin.Context, defaultPageSize, maxPageSize int) CursorPage {
+	return CursorPage{
+		Cursor:   c.Query(CursorQueryParam),
+		PageSize: parsePageSize(c, defaultPageSize, maxPageSize),
+	}
+}
+
+func (p CursorPage) Meta() *Meta {
+	return &Meta{
+		PageSize:   p.PageSize,
+		NextCursor: p.NextCursor,
+		PrevCursor: p.PrevCursor,
+	}
+}
+
+func (p CursorPage) Links(u *url.URL) *Links {
+	link := func(cursor string) string {
+		return withQuery(u, map[string]string{
+			CursorQueryParam:   cursor,
+			PageSizeQueryParam: strconv.Itoa(p.PageSize),
+		})
+	}
+
+	links := &Links{
+		Self:  link(p.Cursor),
+		First: link(""),
+	}
+	if p.NextCursor != "" {
+		links.Next = link(p.NextCursor)
+	}
+	if p.PrevCursor != "" {
+		links.Prev = link(p.PrevCursor)
+	}
+
+	return links
+}
+
+// CursorCodec encodes cursor values into opaque strings.
+// If the codec has a secret, cursors are signed with HMAC-SHA256 so clients cannot forge them.
+type CursorCodec struct {
+	secret []byte
+}
+
+func NewCursorCodec(secret []byte) CursorCodec {
+	return CursorCodec{
+		secret: secret,
+	}
+}
+
+// Encode marshals v to JSON and encodes it as an URL-safe cursor.
+func (cc CursorCodec) Encode(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	cursor := base64.RawURLEncoding.EncodeToString(b)
+	if len(cc.secret) == 0 {
+		return cursor, nil
+	}
+
+	return cursor + "." + base64.RawURLEncoding.EncodeToString(cc.sign(cursor)), nil
+}
+
+// Decode verifies and decodes cursor into v.
+// ErrInvalidCursor is returned if the cursor is malformed or its signature does not match.
+func (cc CursorCodec) Decode(cursor string, v any) error {
+	if len(cc.secret) > 0 {
+		var sig string
+		var found bool
+		cursor, sig, found = strings.Cut(cursor, ".")
+		if !found {
+			return ErrInvalidCursor
+		}
+
+		s, err := base64.RawURLEncoding.DecodeString(sig)
+		if err != nil || !hmac.Equal(s, cc.sign(cursor)) {
+			return ErrInvalidCursor
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
+
+func (cc CursorCodec) sign(cursor string) []byte {
+	mac := hmac.New(sha256.New, cc.secret)
+	mac.Write([]byte(cursor))
+	return mac.Sum(nil)
+}
+
+func parsePageSize(c *gin.Context, defaultPageSize, maxPageSize int) int {
+	pageSize, err := strconv.Atoi(c.Query(PageSizeQueryParam))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if maxPageSize > 0 && pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return pageSize
+}
+
+// requestURL returns the URL the client requested, preferring the URI resolved by TrustedProxyMiddleware, as
+// X-Original-URI can be spoofed when the middleware is not in use. Only URIs starting with a single "/" are used, so
+// that links cannot point off-site.
+func requestURL(c *gin.Context) *url.URL {
+	if info, ok := ClientInfoFrom(c); ok && isPathURI(info.URI) {
+		if u, err := url.ParseRequestURI(info.URI); err == nil {
+			return u
+		}
+	}
+
+	return c.Request.URL
+}
+
+// isPathURI reports whether uri is an absolute path, rather than e.g. the protocol-relative "//example.com/".
+func isPathURI(uri string) bool {
+	return strings.HasPrefix(uri, "/") && !strings.HasPrefix(uri, "//") && !strings.HasPrefix(uri, `/\`)
+}
+
+// withQuery returns the path and query of u with params set, removing params with empty values.
+func withQuery(u *url.URL, params map[string]string) string {
+	q := u.Query()
+	for k, v := range params {
+		if v == "" {
+			q.Del(k)
+			continue
+		}
+		q.Set(k, v)
+	}
+
+	l := url.URL{
+		Path:     u.Path,
+		RawQuery: q.Encode(),
+	}
+
+	return l.String()
+}
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOffsetPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		total    int64
+		expected OffsetPage
+		offset   int
+		links    Links
+	}{
+		{
+			name:     "success_defaults",
+			query:    "",
+			total:    25,
+			expected: OffsetPage{Page: 1, PageSize: 10, Total: 25},
+			offset:   0,
+			links: Links{
+				Self:  "/items?page=1&page_size=10",
+				Next:  "/items?page=2&page_size=10",
+				First: "/items?page=1&page_size=10",
+				Last:  "/items?page=3&page_size=10",
+			},
+		},
+		{
+			name:     "success_middle_page",
+			query:    "page=2&page_size=5&q=x",
+			total:    25,
+			expected: OffsetPage{Page: 2, PageSize: 5, Total: 25},
+			offset:   5,
+			links: Links{
+				Self:  "/items?page=2&page_size=5&q=x",
+				Next:  "/items?page=3&page_size=5&q=x",
+				Prev:  "/items?page=1&page_size=5&q=x",
+				First: "/items?page=1&page_size=5&q=x",
+				Last:  "/items?page=5&page_size=5&q=x",
+			},
+		},
+		{
+			name:     "success_page_size_capped",
+			query:    "page=-1&page_size=1000",
+			total:    0,
+			expected: OffsetPage{Page: 1, PageSize: 50, Total: 0},
+			offset:   0,
+			links: Links{
+				Self:  "/items?page=1&page_size=50",
+				First: "/items?page=1&page_size=50",
+				Last:  "/items?page=1&page_size=50",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest(http.MethodGet, "/items?"+tt.query, nil)
+
+			p := ParseOffsetPage(c, 10, 50)
+			p.Total = tt.total
+
+			a.Equal(tt.expected, p)
+			a.Equal(tt.offset, p.Offset())
+			a.Equal(tt.links, *p.Links(c.Request.URL))
+			a.Equal(tt.total, *p.Meta().Total)
+		})
+	}
+}
+
+func TestCursorPage(t *testing.T) {
+	a := assert.New(t)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/items?cursor=abc", nil)
+
+	p := ParseCursorPage(c, 10, 50)
+	p.NextCursor = "def"
+
+	a.Equal(CursorPage{Cursor: "abc", NextCursor: "def", PageSize: 10}, p)
+	a.Equal(Links{
+		Self:  "/items?cursor=abc&page_size=10",
+		Next:  "/items?cursor=def&page_size=10",
+		First: "/items?page_size=10",
+	}, *p.Links(c.Request.URL))
+	a.Equal(Meta{PageSize: 10, NextCursor: "def"}, *p.Meta())
+}
+
+func TestCursorCodec(t *testing.T) {
+	type cursor struct {
+		ID int64 `json:"id"`
+	}
+
+	tests := []struct {
+		name   string
+		codec  CursorCodec
+		tamper func(string) string
+		err    error
+	}{
+		{
+			name:  "success_unsigned",
+			codec: NewCursorCodec(nil),
+		},
+		{
+			name:  "success_signed",
+			codec: NewCursorCodec([]byte(TestID)),
+		},
+		{
+			name:  "error_signed_tampered",
+			codec: NewCursorCodec([]byte(TestID)),
+			tamper: func(s string) string {
+				forged, _ := NewCursorCodec(nil).Encode(cursor{ID: 2})
+				return forged + s[len(forged):]
+			},
+			err: ErrInvalidCursor,
+		},
+		{
+			name:  "error_signed_missing_signature",
+			codec: NewCursorCodec([]byte(TestID)),
+			tamper: func(s string) string {
+				forged, _ := NewCursorCodec(nil).Encode(cursor{ID: 1})
+				return forged
+			},
+			err: ErrInvalidCursor,
+		},
+		{
+			name:  "error_malformed",
+			codec: NewCursorCodec(nil),
+			tamper: func(s string) string {
+				return "!!"
+			},
+			err: ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			s, err := tt.codec.Encode(cursor{ID: 1})
+			a.NoError(err)
+
+			if tt.tamper != nil {
+				s = tt.tamper(s)
+			}
+
+			var decoded cursor
+			err = tt.codec.Decode(s, &decoded)
+			if tt.err != nil {
+				a.ErrorIs(err, tt.err)
+				return
+			}
+
+			a.NoError(err)
+			a.Equal(cursor{ID: 1}, decoded)
+		})
+	}
+}
+
+func TestOKPage(t *testing.T) {
+	proxy, err := TrustedProxyMiddleware("192.0.2.1")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		proxy       bool
+		originalURI string
+		path        string
+	}{
+		{
+			name:        "success_trusted_proxy",
+			proxy:       true,
+			originalURI: "/api/items?page=2",
+			path:        "/api/items",
+		},
+		{
+			name:        "success_original_uri_ignored_without_proxy",
+			originalURI: "/api/items?page=2",
+			path:        "/items",
+		},
+		{
+			name:        "success_protocol_relative_uri_ignored",
+			proxy:       true,
+			originalURI: "//evil.example/phish",
+			path:        "/items",
+		},
+		{
+			name:        "success_backslash_uri_ignored",
+			proxy:       true,
+			originalURI: `/\evil.example/phish`,
+			path:        "/items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			if tt.proxy {
+				e.Use(proxy)
+			}
+			e.Use(ResponseWrapperMiddleware())
+			e.GET("/items", func(c *gin.Context) {
+				p := ParseOffsetPage(c, 10, 50)
+				p.Total = 11
+				OKPage(c, []int{1, 2}, p)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/items?page=2", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Add("X-Request-ID", TestID)
+			req.Header.Add("X-Original-URI", tt.originalURI)
+			e.ServeHTTP(w, req)
+
+			var resp BaseResponse[[]int]
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+
+			a.NoError(err)
+			a.Equal(http.StatusOK, w.Code)
+			a.Equal([]int{1, 2}, resp.Payload)
+			a.Equal(2, resp.Meta.Page)
+			a.Equal(int64(11), *resp.Meta.Total)
+			a.Equal(tt.path+"?page=2&page_size=10", resp.Links.Self)
+			a.Equal(tt.path+"?page=1&page_size=10", resp.Links.Prev)
+			a.Empty(resp.Links.Next)
+		})
+	}
+}
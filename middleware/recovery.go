@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+)
+
+// PanicSink receives the panics recovered by RecoveryMiddleware, e.g. to report them to an error tracker.
+type PanicSink func(c *gin.Context, recovered any, stack []byte)
+
+// RecoveryMiddleware recovers from panics in the following handlers, logging them with their stack and responding with
+// a 500 BaseResponse carrying the request ID and a generic error. The recovered panics are then passed to the sinks.
+// The middleware should be registered first, so that it covers the other middlewares, except for AccessLogMiddleware
+// which should come before it to log the 500 response.
+func RecoveryMiddleware(sinks ...PanicSink) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := c.Writer
+
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// Let net/http abort the response without logging it.
+				panic(r)
+			}
+
+			stack := debug.Stack()
+
+			log.WithFields(log.Fields{
+				"request_id": getRequestHeaders(c).RequestID,
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+				"panic":      fmt.Sprint(r),
+				"stack":      string(stack),
+			}).Error("recovered from panic")
+
+			for _, sink := range sinks {
+				sink(c, r, stack)
+			}
+
+			// The writers set up by the middlewares following this one may be in an inconsistent state.
+			c.Writer = writer
+			if isBrokenConnection(r) || c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			h := c.Writer.Header()
+			for _, k := range []string{"Content-Encoding", "Content-Length", "ETag", "Last-Modified"} {
+				h.Del(k)
+			}
+			Fail(c, http.StatusInternalServerError, errorPayload{Error: http.StatusText(http.StatusInternalServerError)})
+		}()
+
+		c.Next()
+	}
+}
+
+// isBrokenConnection reports whether the panic was caused by the client closing the connection.
+func isBrokenConnection(r any) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
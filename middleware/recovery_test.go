@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		middlewares []gin.HandlerFunc
+		handler     gin.HandlerFunc
+		status      int
+		wrapped     bool
+		sinkCalled  bool
+	}{
+		{
+			name:    "success_no_panic",
+			handler: func(c *gin.Context) { OK(c, "ok") },
+			status:  http.StatusOK,
+			wrapped: true,
+		},
+		{
+			name:       "success_panic",
+			handler:    func(c *gin.Context) { panic("boom") },
+			status:     http.StatusInternalServerError,
+			wrapped:    true,
+			sinkCalled: true,
+		},
+		{
+			name:        "success_panic_with_writer_middlewares",
+			middlewares: []gin.HandlerFunc{CompressionMiddleware(WithMinCompressSize(1)), ETagMiddleware(), ResponseWrapperMiddleware()},
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusOK)
+				_, _ = c.Writer.Write([]byte(`{"partial":`))
+				panic(fmt.Errorf("boom"))
+			},
+			status:     http.StatusInternalServerError,
+			wrapped:    true,
+			sinkCalled: true,
+		},
+		{
+			name: "success_panic_broken_connection",
+			handler: func(c *gin.Context) {
+				panic(&os.SyscallError{Syscall: "write", Err: syscall.EPIPE})
+			},
+			status:     http.StatusOK,
+			sinkCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			var recovered any
+			sink := func(c *gin.Context, r any, stack []byte) {
+				recovered = r
+				a.NotEmpty(stack)
+			}
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(RecoveryMiddleware(sink))
+			e.Use(tt.middlewares...)
+			e.GET("/", tt.handler)
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, TestID)
+			e.ServeHTTP(w, req)
+
+			a.Equal(tt.status, w.Code)
+			a.Equal(tt.sinkCalled, recovered != nil)
+			a.Empty(w.Header().Get("Content-Encoding"))
+
+			if tt.wrapped {
+				var resp BaseResponse[json.RawMessage]
+				a.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				a.Equal(tt.status, resp.StatusCode)
+				a.Equal(TestID, resp.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+const redactedKeysContextKey = "github.com/Novometrix/util/middleware.redactedKeys"
+
+// RedactMiddleware sets the keys redacted from the response payloads of a route, instead of util.RedactedKeys: the
+// values of the object members whose key is one of keys are redacted. Keys match as a whole, ignoring case, "_" and
+// "-". Redacted strings are replaced by util.RedactedValue, other values by the zero value of their JSON type.
+// Without keys, the key denylist is disabled for the route, see DisableRedaction.
+func RedactMiddleware(keys ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setRedactedKeys(c, keys)
+		c.Next()
+	}
+}
+
+// DisableRedaction disables the key denylist for the response of the request, e.g. for responses carrying tokens
+// on purpose. Struct fields tagged with `redact:"true"` are still redacted by the response helpers.
+func DisableRedaction(c *gin.Context) {
+	setRedactedKeys(c, nil)
+}
+
+func setRedactedKeys(c *gin.Context, keys []string) {
+	if keys == nil {
+		keys = []string{}
+	}
+
+	c.Set(redactedKeysContextKey, keys)
+}
+
+// getRedactedKeys returns the keys set for the request, util.RedactedKeys by default.
+func getRedactedKeys(c *gin.Context) []string {
+	keys, exists := c.Get(redactedKeysContextKey)
+	if !exists {
+		return util.RedactedKeys
+	}
+
+	return keys.([]string)
+}
+
+// redactJSON replaces the values of the object members of the JSON document b whose key is one of keys.
+func redactJSON(b []byte, keys []string) (json.RawMessage, error) {
+	if !mayContainKey(b, keys) {
+		return b, nil
+	}
+
+	t, err := parseValueTree(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = writeValueTreeJSON(&buf, redactValueTree(t, keys))
+	return buf.Bytes(), err
+}
+
+// mayContainKey avoids decoding documents which cannot contain one of keys, comparing them as util.MatchesKey does.
+func mayContainKey(b []byte, keys []string) bool {
+	normalized := bytes.ToLower(b)
+	normalized = bytes.ReplaceAll(normalized, []byte("_"), nil)
+	normalized = bytes.ReplaceAll(normalized, []byte("-"), nil)
+	for _, k := range keys {
+		k = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(k))
+		if bytes.Contains(normalized, []byte(k)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactValueTree(v any, keys []string) any {
+	switch v := v.(type) {
+	case object:
+		redacted := make(object, len(v))
+		for i, m := range v {
+			redacted[i] = m
+			if util.MatchesKey(m.key, keys) {
+				redacted[i].value = redactedJSONValue(m.value)
+			} else {
+				redacted[i].value = redactValueTree(m.value, keys)
+			}
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, e := range v {
+			redacted[i] = redactValueTree(e, keys)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// redactedJSONValue returns the replacement of v keeping its JSON type, as util.Redact does for Go values.
+func redactedJSONValue(v any) any {
+	switch v.(type) {
+	case string:
+		return util.RedactedValue
+	case bool:
+		return false
+	case int64, uint64, float64:
+		return int64(0)
+	case object:
+		return object{}
+	case []any:
+		return []any{}
+	default:
+		return nil
+	}
+}
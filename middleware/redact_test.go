@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseRedaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type User struct {
+		Name         string `json:"name"`
+		PasswordHash string `json:"password_hash" redact:"true"`
+	}
+
+	type Question struct {
+		Text   string `json:"text"`
+		Answer string `json:"answer" redact:"true"`
+	}
+
+	user := User{Name: "user", PasswordHash: "$argon2id$v=19$m=65536,t=1,p=2$c2FsdA$aGFzaA"}
+
+	tests := []struct {
+		name     string
+		redact   []gin.HandlerFunc
+		handler  gin.HandlerFunc
+		expected string
+	}{
+		{
+			name: "success_wrapper_denylist",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, []interface{}{user})
+			},
+			expected: `[{"name":"user","password_hash":"[REDACTED]"}]`,
+		},
+		{
+			name: "success_wrapper_tags_lost",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, Question{Text: "pet", Answer: "cat"})
+			},
+			expected: `{"text":"pet","answer":"cat"}`,
+		},
+		{
+			name: "success_helper_tags_and_denylist",
+			handler: func(c *gin.Context) {
+				OK(c, []interface{}{user, Question{Text: "pet", Answer: "cat"}, gin.H{"secret": "value"}})
+			},
+			expected: `[{"name":"user","password_hash":"[REDACTED]"},{"text":"pet","answer":"[REDACTED]"},{"secret":"[REDACTED]"}]`,
+		},
+		{
+			name: "success_whole_keys_only",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"AccessToken": "value", "token_type": "Bearer", "next_page_token": "abc", "csrf_token": "def"})
+			},
+			expected: `{"AccessToken":"[REDACTED]","token_type":"Bearer","next_page_token":"abc","csrf_token":"def"}`,
+		},
+		{
+			name:   "success_json_types_kept",
+			redact: []gin.HandlerFunc{RedactMiddleware("password_set", "pin", "keys", "scopes", "hint")},
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"password_set": true, "pin": 1234, "keys": gin.H{"a": "b"}, "scopes": []string{"a"}, "hint": nil})
+			},
+			expected: `{"password_set":false,"pin":0,"keys":{},"scopes":[],"hint":null}`,
+		},
+		{
+			name:   "success_route_keys_replace_denylist",
+			redact: []gin.HandlerFunc{RedactMiddleware("pin")},
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"pin": 1234, "secret": "value"})
+			},
+			expected: `{"pin":0,"secret":"value"}`,
+		},
+		{
+			name:   "success_route_disabled",
+			redact: []gin.HandlerFunc{RedactMiddleware()},
+			handler: func(c *gin.Context) {
+				OK(c, []interface{}{user, gin.H{"secret": "value"}})
+			},
+			expected: `[{"name":"user","password_hash":"[REDACTED]"},{"secret":"value"}]`,
+		},
+		{
+			name: "success_response_disabled",
+			handler: func(c *gin.Context) {
+				DisableRedaction(c)
+				c.JSON(http.StatusOK, gin.H{"access_token": "value", "password_hash": "hash"})
+			},
+			expected: `{"access_token":"value","password_hash":"hash"}`,
+		},
+		{
+			name: "success_nothing_to_redact",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"name": "user"})
+			},
+			expected: `{"name":"user"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(ResponseWrapperMiddleware())
+			e.GET("/", append(tt.redact, tt.handler)...)
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(EnvelopeHeader, "none")
+			e.ServeHTTP(w, req)
+
+			assert.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDContextKey is the gin context key the request ID is stored under.
+	RequestIDContextKey = "request_id"
+
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+type requestID struct {
+	generate func() (string, error)
+	validate func(string) bool
+}
+
+// WithRequestIDGenerator sets the function used to generate request IDs. Defaults to util.NewUUIDv7.
+func WithRequestIDGenerator(g func() (string, error)) func(*requestID) {
+	return func(r *requestID) {
+		r.generate = g
+	}
+}
+
+// WithRequestIDValidator sets the function used to validate incoming request IDs.
+// Invalid request IDs are replaced by a generated one. Defaults to IsValidRequestID.
+func WithRequestIDValidator(v func(string) bool) func(*requestID) {
+	return func(r *requestID) {
+		r.validate = v
+	}
+}
+
+// RequestIDMiddleware makes sure every request has a request ID.
+// The ID is read from the X-Request-ID header, or generated if it is missing or invalid.
+// It is then stored in the gin context, the request context.Context and the X-Request-ID request and response headers.
+// The middleware must be registered before ResponseWrapperMiddleware for the ID to be part of the BaseResponse.
+func RequestIDMiddleware(options ...func(*requestID)) gin.HandlerFunc {
+	r := &requestID{
+		generate: util.NewUUIDv7,
+		validate: IsValidRequestID,
+	}
+
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !r.validate(id) {
+			var err error
+			id, err = r.generate()
+			if err != nil {
+				log.Errorf("failed to generate request id with error: %v", err)
+				c.Request.Header.Del(RequestIDHeader)
+				c.Next()
+				return
+			}
+		}
+
+		c.Request.Header.Set(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(ContextWithRequestID(c.Request.Context(), id))
+		c.Set(RequestIDContextKey, id)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+// IsValidRequestID reports whether id is non-empty, at most 128 characters long and only contains
+// alphanumerics, '-', '_', '.' and ':'.
+func IsValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID stored by RequestIDMiddleware.
+// ctx may either be the *gin.Context or the context.Context of the request.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	if c, ok := ctx.(*gin.Context); ok {
+		if id := c.GetString(RequestIDContextKey); id != "" {
+			return id, true
+		}
+		if c.Request == nil {
+			return "", false
+		}
+		ctx = c.Request.Context()
+	}
+
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// SetRequestIDHeader sets the X-Request-ID header of an outgoing request from its context, if it is not already set.
+func SetRequestIDHeader(req *http.Request) {
+	if req.Header.Get(RequestIDHeader) != "" {
+		return
+	}
+
+	if id, ok := RequestIDFrom(req.Context()); ok {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
+
+// RequestIDTransport is a http.RoundTripper propagating the request ID of the request context to outgoing requests.
+type RequestIDTransport struct {
+	// Base is the underlying transport. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if id, ok := RequestIDFrom(req.Context()); ok && req.Header.Get(RequestIDHeader) == "" {
+		// A RoundTripper must not modify the request, so the header is set on a clone.
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	return base.RoundTrip(req)
+}
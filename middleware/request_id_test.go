@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const generatedID = "generated-id"
+
+	tests := []struct {
+		name      string
+		requestID string
+		options   []func(*requestID)
+		expected  string
+	}{
+		{
+			name:      "success_incoming",
+			requestID: TestID,
+			expected:  TestID,
+		},
+		{
+			name:     "success_generated_missing",
+			options:  []func(*requestID){WithRequestIDGenerator(func() (string, error) { return generatedID, nil })},
+			expected: generatedID,
+		},
+		{
+			name:      "success_generated_invalid",
+			requestID: "<script>",
+			options:   []func(*requestID){WithRequestIDGenerator(func() (string, error) { return generatedID, nil })},
+			expected:  generatedID,
+		},
+		{
+			name:      "success_custom_validator",
+			requestID: TestID,
+			options: []func(*requestID){
+				WithRequestIDGenerator(func() (string, error) { return generatedID, nil }),
+				WithRequestIDValidator(func(s string) bool { return false }),
+			},
+			expected: generatedID,
+		},
+		{
+			name:     "error_generator",
+			options:  []func(*requestID){WithRequestIDGenerator(func() (string, error) { return "", errors.New(TestID) })},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(RequestIDMiddleware(tt.options...), ResponseWrapperMiddleware())
+
+			var fromGin, fromContext string
+			e.POST("/", func(c *gin.Context) {
+				fromGin, _ = RequestIDFrom(c)
+				fromContext, _ = RequestIDFrom(c.Request.Context())
+				c.JSON(http.StatusOK, nil)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Add(RequestIDHeader, tt.requestID)
+			}
+			e.ServeHTTP(w, req)
+
+			var resp BaseResponse[interface{}]
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+			a.Equal(tt.expected, fromGin)
+			a.Equal(tt.expected, fromContext)
+			a.Equal(tt.expected, w.Header().Get(RequestIDHeader))
+			a.Equal(tt.expected, resp.RequestID)
+		})
+	}
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{name: "success_uuid", id: TestID, expected: true},
+		{name: "success_ulid", id: "01ARZ3NDEKTSV4RRFFQ69G5FAV", expected: true},
+		{name: "error_empty", id: "", expected: false},
+		{name: "error_too_long", id: strings.Repeat("a", maxRequestIDLength+1), expected: false},
+		{name: "error_invalid_characters", id: "abc\r\nX-Injected: 1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsValidRequestID(tt.id))
+		})
+	}
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	a := assert.New(t)
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: RequestIDTransport{}}
+
+	req, _ := http.NewRequestWithContext(ContextWithRequestID(context.Background(), TestID), http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+	a.NoError(err)
+	_ = resp.Body.Close()
+
+	a.Equal(TestID, received)
+	a.Empty(req.Header.Get(RequestIDHeader))
+
+	SetRequestIDHeader(req)
+	a.Equal(TestID, req.Header.Get(RequestIDHeader))
+}
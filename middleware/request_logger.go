@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+
+	// LoggerContextKey is the gin context key the request-scoped logger is stored under.
+	LoggerContextKey = "logger"
+
+	loggerSubjectKeyContextKey = "github.com/Novometrix/util/middleware.loggerSubjectKey"
+)
+
+type loggerKey struct{}
+
+type requestLogger struct {
+	logger     log.FieldLogger
+	subjectKey string
+}
+
+// WithBaseLogger sets the logger the request-scoped loggers are derived from. Defaults to the logrus standard logger.
+func WithBaseLogger(l log.FieldLogger) func(*requestLogger) {
+	return func(rl *requestLogger) {
+		rl.logger = l
+	}
+}
+
+// WithLoggerSubjectContextKey sets the gin context key of the authenticated claims, see
+// authentication.WithContextKey. Defaults to "user".
+func WithLoggerSubjectContextKey(k string) func(*requestLogger) {
+	return func(rl *requestLogger) {
+		rl.subjectKey = k
+	}
+}
+
+// RequestLoggerMiddleware stores a logger pre-populated with the request ID, the route, the trace and span IDs of the
+// W3C traceparent header and the authenticated subject in the gin context and the request context.Context,
+// see LoggerFrom.
+// The subject is only known to the loggers of the request context.Context if the middleware is registered after
+// the authentication middleware. Loggers retrieved from the gin context pick it up whenever it is available.
+func RequestLoggerMiddleware(options ...func(*requestLogger)) gin.HandlerFunc {
+	rl := &requestLogger{
+		logger:     log.StandardLogger(),
+		subjectKey: "user",
+	}
+
+	for _, opt := range options {
+		opt(rl)
+	}
+
+	return func(c *gin.Context) {
+		fields := log.Fields{
+			"route": c.FullPath(),
+		}
+		if id, ok := RequestIDFrom(c); ok {
+			fields["request_id"] = id
+		} else if id = getRequestHeaders(c).RequestID; id != "" {
+			fields["request_id"] = id
+		}
+		if traceID, spanID, ok := parseTraceParent(c.GetHeader(TraceParentHeader)); ok {
+			fields["trace_id"] = traceID
+			fields["span_id"] = spanID
+		}
+		if subject := subjectFrom(c, rl.subjectKey); subject != "" {
+			fields["subject"] = subject
+		}
+
+		entry := rl.logger.WithFields(fields)
+
+		c.Set(LoggerContextKey, entry)
+		c.Set(loggerSubjectKeyContextKey, rl.subjectKey)
+		c.Request = c.Request.WithContext(ContextWithLogger(c.Request.Context(), entry))
+
+		c.Next()
+	}
+}
+
+// ContextWithLogger returns a copy of ctx carrying the logger.
+func ContextWithLogger(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// LoggerFrom returns the request-scoped logger stored by RequestLoggerMiddleware.
+// ctx may either be the *gin.Context or the context.Context of the request. If there is no request-scoped logger,
+// a logger of the logrus standard logger is returned, with the request ID if ctx carries one.
+func LoggerFrom(ctx context.Context) *log.Entry {
+	if c, ok := ctx.(*gin.Context); ok {
+		if entry, exists := c.Get(LoggerContextKey); exists {
+			return withSubject(c, entry.(*log.Entry))
+		}
+		if c.Request != nil {
+			ctx = c.Request.Context()
+		}
+	}
+
+	if entry, ok := ctx.Value(loggerKey{}).(*log.Entry); ok {
+		return entry
+	}
+
+	entry := log.NewEntry(log.StandardLogger())
+	if id, ok := RequestIDFrom(ctx); ok {
+		entry = entry.WithField("request_id", id)
+	}
+
+	return entry
+}
+
+// withSubject adds the authenticated subject to entry if it was not known when the logger was created.
+func withSubject(c *gin.Context, entry *log.Entry) *log.Entry {
+	if _, exists := entry.Data["subject"]; exists {
+		return entry
+	}
+
+	if subject := subjectFrom(c, c.GetString(loggerSubjectKeyContextKey)); subject != "" {
+		return entry.WithField("subject", subject)
+	}
+
+	return entry
+}
+
+// parseTraceParent returns the trace and parent span IDs of a W3C traceparent header,
+// e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceParent(h string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) ||
+		parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
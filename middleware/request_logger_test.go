@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		traceParent string
+		expected    map[string]interface{}
+	}{
+		{
+			name:        "success",
+			traceParent: "00-" + traceID + "-" + spanID + "-01",
+			expected: map[string]interface{}{
+				"request_id": TestID,
+				"route":      "/items/:id",
+				"trace_id":   traceID,
+				"span_id":    spanID,
+			},
+		},
+		{
+			name:        "success_invalid_traceparent",
+			traceParent: "00-" + traceID + "-" + spanID,
+			expected: map[string]interface{}{
+				"request_id": TestID,
+				"route":      "/items/:id",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			logger, hook := test.NewNullLogger()
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(RequestIDMiddleware(), RequestLoggerMiddleware(WithBaseLogger(logger)))
+			e.GET("/items/:id", func(c *gin.Context) {
+				LoggerFrom(c.Request.Context()).Info("from context")
+
+				c.Set("user", jwt.MapClaims{"sub": "subject"})
+				LoggerFrom(c).Info("from gin")
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/items/1", nil)
+			req.Header.Set(RequestIDHeader, TestID)
+			req.Header.Set(TraceParentHeader, tt.traceParent)
+			e.ServeHTTP(w, req)
+
+			a.Len(hook.Entries, 2)
+			a.Equal(tt.expected, map[string]interface{}(hook.Entries[0].Data))
+
+			tt.expected["subject"] = "subject"
+			a.Equal(tt.expected, map[string]interface{}(hook.Entries[1].Data))
+		})
+	}
+}
+
+func TestLoggerFrom_Fallback(t *testing.T) {
+	entry := LoggerFrom(ContextWithRequestID(context.Background(), TestID))
+
+	assert.Equal(t, TestID, entry.Data["request_id"])
+}
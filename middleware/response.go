@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/Novometrix/util/util"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	requestHeadersContextKey = "github.com/Novometrix/util/middleware.requestHeaders"
+	wrappedContextKey        = "github.com/Novometrix/util/middleware.wrapped"
+)
+
+type errorPayload struct {
+	Error string `json:"error"`
+}
+
+// OK writes payload as a 200 BaseResponse.
+func OK[T any](c *gin.Context, payload T) {
+	Respond(c, http.StatusOK, payload)
+}
+
+// Created writes payload as a 201 BaseResponse.
+func Created[T any](c *gin.Context, payload T) {
+	Respond(c, http.StatusCreated, payload)
+}
+
+// Fail aborts the request and writes payload as a BaseResponse with the given status.
+func Fail[T any](c *gin.Context, status int, payload T) {
+	c.Abort()
+	Respond(c, status, payload)
+}
+
+// Respond writes payload as a BaseResponse with the given status, in the envelope version negotiated with the client.
+// The response is marked as wrapped, so ResponseWrapperMiddleware will write it as is instead of re-wrapping it.
+func Respond[T any](c *gin.Context, status int, payload T) {
+	writeResponse(c, newBaseResponse(c, status, payload))
+}
+
+// OKPage writes payload as a 200 BaseResponse with the pagination meta and links of page.
+func OKPage[T any](c *gin.Context, payload T, page Paginator) {
+	resp := newBaseResponse(c, http.StatusOK, payload)
+	resp.Meta = page.Meta()
+	resp.Links = page.Links(requestURL(c))
+
+	writeResponse(c, resp)
+}
+
+// writeResponse writes resp in the envelope version and format negotiated with the client.
+func writeResponse[T any](c *gin.Context, resp BaseResponse[T]) {
+	c.Set(wrappedContextKey, true)
+	c.Writer.Header().Add("Vary", "Accept, "+EnvelopeHeader)
+
+	// Typed payloads are redacted through their struct tags, before they are marshaled, and through the key denylist.
+	resp.Payload = util.RedactKeys(resp.Payload, getRedactedKeys(c))
+
+	version := getEnvelopeVersion(c)
+	withRequestID := func(id string) any {
+		r := resp
+		r.RequestID = id
+		return envelope(version, r)
+	}
+	if fs, ok := getFieldSet(c); ok && isPrunedStatus(resp.StatusCode) {
+		b, err := json.Marshal(resp.Payload)
+		if err == nil {
+			var pruned json.RawMessage
+			pruned, err = fs.pruneJSON(b)
+			withRequestID = func(id string) any {
+				r := withPayload(resp, pruned)
+				r.RequestID = id
+				return envelope(version, r)
+			}
+		}
+		if err != nil {
+			log.Errorf("failed to prune response fields with error: %v", err)
+		}
+	}
+
+	enc := getEncoder(c)
+	b, err := enc.Encode(withRequestID(resp.RequestID))
+	if err != nil {
+		log.Errorf("failed to encode response with error: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	setETagRepresentation(c, func() ([]byte, error) {
+		return enc.Encode(withRequestID(""))
+	})
+
+	c.Data(resp.StatusCode, enc.ContentType(), b)
+}
+
+func withPayload[T, U any](resp BaseResponse[T], payload U) BaseResponse[U] {
+	return BaseResponse[U]{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.RequestID,
+		Payload:    payload,
+		Meta:       resp.Meta,
+		Links:      resp.Links,
+	}
+}
+
+func newBaseResponse[T any](c *gin.Context, status int, payload T) BaseResponse[T] {
+	return BaseResponse[T]{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		RequestID:  getRequestHeaders(c).RequestID,
+		Payload:    payload,
+	}
+}
+
+// getRequestHeaders returns the headers bound by ResponseWrapperMiddleware, binding them if the middleware is not in use.
+func getRequestHeaders(c *gin.Context) DefaultRequestHeaders {
+	if h, exists := c.Get(requestHeadersContextKey); exists {
+		return h.(DefaultRequestHeaders)
+	}
+
+	reqHeaders := DefaultRequestHeaders{}
+	if err := c.ShouldBindHeader(&reqHeaders); err != nil {
+		log.Errorf("failed to bind request headers with error: %v", err)
+	}
+	c.Set(requestHeadersContextKey, reqHeaders)
+
+	return reqHeaders
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespondHelpers(t *testing.T) {
+	type InputStruct struct {
+		ID   int64
+		Name string
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	input := InputStruct{
+		ID:   1<<62 + 1,
+		Name: "hi",
+	}
+
+	tests := []struct {
+		name           string
+		handler        gin.HandlerFunc
+		expectedStatus int
+		aborted        bool
+	}{
+		{
+			name: "success_OK",
+			handler: func(c *gin.Context) {
+				OK(c, input)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "success_Created",
+			handler: func(c *gin.Context) {
+				Created(c, input)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "success_Fail",
+			handler: func(c *gin.Context) {
+				Fail(c, http.StatusBadRequest, input)
+			},
+			expectedStatus: http.StatusBadRequest,
+			aborted:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, wrapped := range []bool{true, false} {
+			t.Run(tt.name, func(t *testing.T) {
+				a := assert.New(t)
+
+				w := httptest.NewRecorder()
+				_, e := gin.CreateTestContext(w)
+
+				if wrapped {
+					e.Use(ResponseWrapperMiddleware())
+				}
+
+				afterHandler := false
+				e.POST("/", tt.handler, func(c *gin.Context) {
+					afterHandler = true
+				})
+
+				req, _ := http.NewRequest(http.MethodPost, "/", nil)
+				req.Header.Add("X-Request-ID", TestID)
+				e.ServeHTTP(w, req)
+
+				var resp BaseResponse[InputStruct]
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
+
+				a.NoError(err)
+				a.Equal("application/json; charset=utf-8", w.Header().Get("Content-Type"))
+				a.Equal(tt.expectedStatus, w.Code)
+				a.Equal(tt.expectedStatus, resp.StatusCode)
+				a.Equal(http.StatusText(tt.expectedStatus), resp.Status)
+				a.Equal(TestID, resp.RequestID)
+				a.Equal(input, resp.Payload)
+				a.Equal(tt.aborted, !afterHandler)
+			})
+		}
+	}
+}
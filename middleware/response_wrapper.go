@@ -9,10 +9,12 @@ import (
 )
 
 type BaseResponse[T any] struct {
-	Status     string      `json:"status"`
-	StatusCode int         `json:"status_code"`
-	RequestID  string      `json:"request_id"`
-	Payload    T `json:"payload,omitempty"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code"`
+	RequestID  string `json:"request_id"`
+	Payload    T      `json:"payload,omitempty"`
+	Meta       *Meta  `json:"meta,omitempty"`
+	Links      *Links `json:"links,omitempty"`
 }
 
 type DefaultRequestHeaders struct {
@@ -25,48 +27,95 @@ type DefaultRequestHeaders struct {
 type responseWrapper struct {
 	gin.ResponseWriter
 	Headers DefaultRequestHeaders
+	context *gin.Context
 }
 
 func (rw responseWrapper) Write(b []byte) (int, error) {
+	if rw.context != nil && rw.context.GetBool(wrappedContextKey) {
+		return rw.ResponseWriter.Write(b)
+	}
+
+	version := EnvelopeV1
+	var enc Encoder = jsonEncoder{}
+	var fs fieldSet
+	var redactedKeys []string
+	if rw.context != nil {
+		version = getEnvelopeVersion(rw.context)
+		enc = getEncoder(rw.context)
+		if isPrunedStatus(rw.ResponseWriter.Status()) {
+			fs, _ = getFieldSet(rw.context)
+		}
+		redactedKeys = getRedactedKeys(rw.context)
+	}
+	rw.ResponseWriter.Header().Add("Vary", "Accept, "+EnvelopeHeader)
+
+	if version == EnvelopeNone && (!json.Valid(b) || (isJSONEncoder(enc) && fs == nil && !mayContainKey(b, redactedKeys))) {
+		return rw.ResponseWriter.Write(b)
+	}
+
 	httpStatus := rw.ResponseWriter.Status()
 
-	var payload interface{}
+	// The payload is spliced in as is rather than decoded, preserving number precision and key ordering.
+	var payload json.RawMessage
+	if json.Valid(b) {
+		payload = b
+		if fs != nil {
+			pruned, err := fs.pruneJSON(b)
+			if err != nil {
+				log.Errorf("failed to prune response fields with error: %v", err)
+			} else {
+				payload = pruned
+			}
+		}
 
-	_ = json.Unmarshal(b, &payload)
+		// Only the key denylist can be applied here, struct tags are lost once the payload is marshaled.
+		redacted, err := redactJSON(payload, redactedKeys)
+		if err != nil {
+			log.Errorf("failed to redact response with error: %v", err)
+		} else {
+			payload = redacted
+		}
+	}
 
-	resp := BaseResponse[interface{}]{
+	resp := BaseResponse[json.RawMessage]{
 		Status:     http.StatusText(httpStatus),
 		StatusCode: httpStatus,
 		RequestID:  rw.Headers.RequestID,
 		Payload:    payload,
 	}
 
-	r, err := json.Marshal(resp)
+	r, err := enc.Encode(envelope(version, resp))
 	if err != nil {
 		log.Errorf("failed to marshal wrapped response with error: %v", err)
 		return rw.ResponseWriter.Write(b)
 	}
 
-	rw.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if rw.context != nil {
+		setETagRepresentation(rw.context, func() ([]byte, error) {
+			resp.RequestID = ""
+			return enc.Encode(envelope(version, resp))
+		})
+	}
+
+	rw.ResponseWriter.Header().Set("Content-Type", enc.ContentType())
 
 	return rw.ResponseWriter.Write(r)
 }
 
+// ResponseWrapperMiddleware wraps the responses written by the handlers, e.g. with c.JSON, in a BaseResponse.
+// The values of the keys of util.RedactedKeys are redacted from the payloads, see RedactMiddleware and
+// DisableRedaction. Struct fields tagged with `redact:"true"` are only redacted when written with the response
+// helpers such as OK and Respond, the tags being lost once a payload is marshaled by c.JSON.
 func ResponseWrapperMiddleware(ignoredMethods ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if util.SliceContains(ignoredMethods, c.Request.Method) {
 			c.Next()
 			return
 		}
-		reqHeaders := DefaultRequestHeaders{}
-
-		if err := c.ShouldBindHeader(&reqHeaders); err != nil {
-			log.Errorf("failed to bind request headers with error: %v", err)
-		}
-
 		rw := &responseWrapper{
 			ResponseWriter: c.Writer,
-			Headers:        reqHeaders,
+			Headers:        getRequestHeaders(c),
+			context:        c,
 		}
 		c.Writer = rw
 		c.Next()
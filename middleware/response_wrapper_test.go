@@ -238,3 +238,54 @@ func TestResponseWrapper_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseWrapper_Write_PreservesPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "success_large_integer",
+			body:     `{"id":9007199254740993}`,
+			expected: `{"id":9007199254740993}`,
+		},
+		{
+			name:     "success_key_ordering",
+			body:     `{"b":1,"a":{"d":2,"c":3}}`,
+			expected: `{"b":1,"a":{"d":2,"c":3}}`,
+		},
+		{
+			name:     "success_invalid_json_omitted",
+			body:     `not json`,
+			expected: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			e.Use(ResponseWrapperMiddleware())
+			e.POST("/", func(c *gin.Context) {
+				c.Data(http.StatusOK, "application/json", []byte(tt.body))
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Add("X-Request-ID", TestID)
+			e.ServeHTTP(w, req)
+
+			var resp BaseResponse[json.RawMessage]
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+
+			a.NoError(err)
+			a.Equal(tt.expected, string(resp.Payload))
+			a.Equal(TestID, resp.RequestID)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"strings"
+)
+
+const (
+	OriginalURIHeader        = "X-Original-URI"
+	OriginalRemoteAddrHeader = "X-Original-Remote-Addr"
+	OriginalHostHeader       = "X-Original-Host"
+	ForwardedHeader          = "Forwarded"
+	ForwardedForHeader       = "X-Forwarded-For"
+	ForwardedProtoHeader     = "X-Forwarded-Proto"
+	ForwardedHostHeader      = "X-Forwarded-Host"
+
+	// ClientInfoContextKey is the gin context key the ClientInfo is stored under.
+	ClientInfoContextKey = "client_info"
+)
+
+var proxyHeaders = []string{
+	OriginalURIHeader,
+	OriginalRemoteAddrHeader,
+	OriginalHostHeader,
+	ForwardedHeader,
+	ForwardedForHeader,
+	ForwardedProtoHeader,
+	ForwardedHostHeader,
+}
+
+// ClientInfo describes the request as sent by the client, before it went through any trusted proxies.
+type ClientInfo struct {
+	IP     string
+	Scheme string
+	Host   string
+	URI    string
+}
+
+type forwardedElement struct {
+	For   string
+	Proto string
+	Host  string
+}
+
+// TrustedProxyMiddleware resolves the ClientInfo of the request and stores it in the gin context.
+// The X-Original-*, Forwarded and X-Forwarded-* headers are only honored when the request comes from one of the
+// trustedProxies, given as IP addresses or CIDRs. Otherwise, the headers are removed from the request so that they
+// cannot be spoofed through DefaultRequestHeaders.
+// The X-Original-* headers are then set to the resolved values, so the middleware must be registered before
+// ResponseWrapperMiddleware.
+func TrustedProxyMiddleware(trustedProxies ...string) (gin.HandlerFunc, error) {
+	nets := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, p := range trustedProxies {
+		n, err := parseCIDR(p)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+
+	isTrusted := func(ip net.IP) bool {
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *gin.Context) {
+		info := ClientInfo{
+			IP:     remoteIP(c.Request.RemoteAddr),
+			Scheme: "http",
+			Host:   c.Request.Host,
+			URI:    c.Request.URL.RequestURI(),
+		}
+		if c.Request.TLS != nil {
+			info.Scheme = "https"
+		}
+
+		if ip := net.ParseIP(info.IP); ip != nil && isTrusted(ip) {
+			info = resolveForwarded(c, info, isTrusted)
+		}
+
+		for _, h := range proxyHeaders {
+			c.Request.Header.Del(h)
+		}
+		c.Request.Header.Set(OriginalURIHeader, info.URI)
+		c.Request.Header.Set(OriginalRemoteAddrHeader, info.IP)
+		c.Request.Header.Set(OriginalHostHeader, info.Host)
+
+		c.Set(ClientInfoContextKey, info)
+		c.Next()
+	}, nil
+}
+
+// ClientInfoFrom returns the ClientInfo stored by TrustedProxyMiddleware.
+func ClientInfoFrom(c *gin.Context) (ClientInfo, bool) {
+	info, exists := c.Get(ClientInfoContextKey)
+	if !exists {
+		return ClientInfo{}, false
+	}
+
+	return info.(ClientInfo), true
+}
+
+func resolveForwarded(c *gin.Context, info ClientInfo, isTrusted func(net.IP) bool) ClientInfo {
+	var elements []forwardedElement
+	if f := c.Request.Header.Values(ForwardedHeader); len(f) > 0 {
+		elements = parseForwarded(strings.Join(f, ","))
+	} else if xff := c.Request.Header.Values(ForwardedForHeader); len(xff) > 0 {
+		for _, ip := range splitHeaderList(xff) {
+			elements = append(elements, forwardedElement{For: ip})
+		}
+
+		// The protocols and hosts belong to the hops of X-Forwarded-For when there are as many, otherwise the first
+		// ones are used.
+		protos := splitHeaderList(c.Request.Header.Values(ForwardedProtoHeader))
+		hosts := splitHeaderList(c.Request.Header.Values(ForwardedHostHeader))
+		for i := range elements {
+			if len(protos) == len(elements) {
+				elements[i].Proto = protos[i]
+			}
+			if len(hosts) == len(elements) {
+				elements[i].Host = hosts[i]
+			}
+		}
+		if len(protos) > 0 && len(protos) != len(elements) {
+			info.Scheme = strings.ToLower(protos[0])
+		}
+		if len(hosts) > 0 && len(hosts) != len(elements) {
+			info.Host = hosts[0]
+		}
+	}
+
+	// The client is the rightmost address that is not a trusted proxy, as the addresses to its left may be spoofed.
+	var client *forwardedElement
+	for i := len(elements) - 1; i >= 0; i-- {
+		ip := net.ParseIP(remoteIP(elements[i].For))
+		if ip == nil {
+			break
+		}
+		client = &elements[i]
+		if !isTrusted(ip) {
+			break
+		}
+	}
+
+	if client != nil {
+		info.IP = remoteIP(client.For)
+		if client.Proto != "" {
+			info.Scheme = strings.ToLower(client.Proto)
+		}
+		if client.Host != "" {
+			info.Host = client.Host
+		}
+	}
+
+	if ip := net.ParseIP(remoteIP(c.GetHeader(OriginalRemoteAddrHeader))); ip != nil {
+		info.IP = ip.String()
+	}
+	if h := c.GetHeader(OriginalHostHeader); h != "" {
+		info.Host = h
+	}
+	if uri := c.GetHeader(OriginalURIHeader); uri != "" {
+		info.URI = uri
+	}
+
+	return info
+}
+
+// splitHeaderList splits the comma-separated values of a header, trimming them and skipping the empty ones.
+func splitHeaderList(values []string) []string {
+	var list []string
+	for _, v := range strings.Split(strings.Join(values, ","), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// parseForwarded parses a RFC 7239 Forwarded header value.
+func parseForwarded(value string) []forwardedElement {
+	var elements []forwardedElement
+	for _, e := range strings.Split(value, ",") {
+		var el forwardedElement
+		for _, pair := range strings.Split(e, ";") {
+			k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found {
+				continue
+			}
+			v = strings.Trim(v, `"`)
+
+			switch strings.ToLower(k) {
+			case "for":
+				el.For = v
+			case "proto":
+				el.Proto = v
+			case "host":
+				el.Host = v
+			}
+		}
+		elements = append(elements, el)
+	}
+
+	return elements
+}
+
+// remoteIP strips the port and IPv6 brackets from addr.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+func parseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", s)
+		}
+
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	trustedProxies := []string{"10.0.0.0/8", "2001:db8::1"}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   ClientInfo
+	}{
+		{
+			name:       "success_untrusted_headers_ignored",
+			remoteAddr: "203.0.113.7:1234",
+			headers: map[string]string{
+				OriginalRemoteAddrHeader: "198.51.100.1",
+				OriginalHostHeader:       "spoofed.example",
+				OriginalURIHeader:        "/spoofed",
+				ForwardedForHeader:       "198.51.100.1",
+			},
+			expected: ClientInfo{IP: "203.0.113.7", Scheme: "http", Host: "api.example", URI: "/items?a=1"},
+		},
+		{
+			name:       "success_trusted_original_headers",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				OriginalRemoteAddrHeader: "198.51.100.1",
+				OriginalHostHeader:       "public.example",
+				OriginalURIHeader:        "/api/items?a=1",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "http", Host: "public.example", URI: "/api/items?a=1"},
+		},
+		{
+			name:       "success_trusted_forwarded",
+			remoteAddr: "[2001:db8::1]:1234",
+			headers: map[string]string{
+				ForwardedHeader: `for=198.51.100.9, for="[2001:db8:cafe::17]:4711";proto=HTTPS;host=public.example, for=10.0.0.2`,
+			},
+			expected: ClientInfo{IP: "2001:db8:cafe::17", Scheme: "https", Host: "public.example", URI: "/items?a=1"},
+		},
+		{
+			name:       "success_trusted_x_forwarded_for",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				ForwardedForHeader:   "198.51.100.9, 198.51.100.1, 10.0.0.2",
+				ForwardedProtoHeader: "https",
+				ForwardedHostHeader:  "public.example",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "https", Host: "public.example", URI: "/items?a=1"},
+		},
+		{
+			name:       "success_trusted_x_forwarded_lists",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				ForwardedForHeader:   "198.51.100.1",
+				ForwardedProtoHeader: " HTTPS , http",
+				ForwardedHostHeader:  "public.example, internal.example",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "https", Host: "public.example", URI: "/items?a=1"},
+		},
+		{
+			name:       "success_trusted_x_forwarded_hops",
+			remoteAddr: "10.1.2.3:1234",
+			headers: map[string]string{
+				ForwardedForHeader:   "198.51.100.9, 198.51.100.1, 10.0.0.2",
+				ForwardedProtoHeader: "http, https, http",
+				ForwardedHostHeader:  "spoofed.example, public.example, internal.example",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "https", Host: "public.example", URI: "/items?a=1"},
+		},
+		{
+			name:       "success_trusted_no_headers",
+			remoteAddr: "10.1.2.3:1234",
+			expected:   ClientInfo{IP: "10.1.2.3", Scheme: "http", Host: "api.example", URI: "/items?a=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			mw, err := TrustedProxyMiddleware(trustedProxies...)
+			a.NoError(err)
+
+			w := httptest.NewRecorder()
+			_, e := gin.CreateTestContext(w)
+
+			var info ClientInfo
+			var headers DefaultRequestHeaders
+			e.Use(mw)
+			e.GET("/items", func(c *gin.Context) {
+				info, _ = ClientInfoFrom(c)
+				_ = c.ShouldBindHeader(&headers)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "http://api.example/items?a=1", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			e.ServeHTTP(w, req)
+
+			a.Equal(tt.expected, info)
+			a.Equal(tt.expected.IP, headers.RemoteAddress)
+			a.Equal(tt.expected.Host, headers.Host)
+			a.Equal(tt.expected.URI, headers.RequestURI)
+		})
+	}
+}
+
+func TestTrustedProxyMiddleware_InvalidProxy(t *testing.T) {
+	_, err := TrustedProxyMiddleware("10.0.0.0/8", "not-an-ip")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package util
+
+import (
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+)
+
+const (
+	// RedactedValue replaces the value of redacted string fields.
+	RedactedValue = "[REDACTED]"
+)
+
+// RedactedKeys is the denylist of map keys whose values are redacted. Keys match as a whole, ignoring case, "_" and
+// "-", e.g. "access_token" matches "AccessToken" but "next_page_token" matches none.
+var RedactedKeys = []string{
+	"password",
+	"passwd",
+	"password_hash",
+	"secret",
+	"client_secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"authorization",
+	"api_key",
+	"private_key",
+}
+
+// IsRedactedKey reports whether the value of key should be redacted according to RedactedKeys.
+func IsRedactedKey(key string) bool {
+	return MatchesKey(key, RedactedKeys)
+}
+
+// MatchesKey reports whether key is one of keys, ignoring case, "_" and "-".
+func MatchesKey(key string, keys []string) bool {
+	key = normalizeKey(key)
+	for _, k := range keys {
+		if key == normalizeKey(k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
+
+// Redact returns a deep copy of v where struct fields tagged with `redact:"true"` and the values of map keys
+// matching RedactedKeys are redacted. Redacted strings are replaced by RedactedValue, other types by their zero value.
+// v itself is left untouched.
+func Redact[T any](v T) T {
+	return RedactKeys(v, RedactedKeys)
+}
+
+// RedactKeys is Redact with the map keys to redact given by keys instead of RedactedKeys. With no keys, only the
+// tagged struct fields are redacted.
+func RedactKeys[T any](v T, keys []string) T {
+	r := redactor{keys: keys, copies: map[visit]reflect.Value{}}
+	rv := reflect.ValueOf(&v).Elem()
+	redacted, _ := r.redactValue(rv).Interface().(T)
+	return redacted
+}
+
+// visit identifies a pointer, map or slice already copied, so that cyclic values are copied once rather than
+// recursing forever.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+type redactor struct {
+	keys   []string
+	copies map[visit]reflect.Value
+}
+
+func (r redactor) redactValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if c, ok := r.copies[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		r.copies[key] = c
+		c.Elem().Set(r.redactValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(r.redactValue(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !c.Field(i).CanSet() {
+				continue
+			}
+			if f.Tag.Get("redact") == "true" {
+				c.Field(i).Set(redactedValue(f.Type))
+				continue
+			}
+			c.Field(i).Set(r.redactValue(v.Field(i)))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() || isScalar(v.Type().Elem()) {
+			return v
+		}
+		key := visit{ptr: v.Pointer(), len: v.Len(), typ: v.Type()}
+		if c, ok := r.copies[key]; ok {
+			return c
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		r.copies[key] = c
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(r.redactValue(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(r.redactValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if c, ok := r.copies[key]; ok {
+			return c
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		r.copies[key] = c
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key()
+			if k.Kind() == reflect.String && MatchesKey(k.String(), r.keys) {
+				c.SetMapIndex(k, redactedValue(v.Type().Elem()))
+				continue
+			}
+			c.SetMapIndex(k, r.redactValue(iter.Value()))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+func redactedValue(t reflect.Type) reflect.Value {
+	switch {
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(RedactedValue).Convert(t)
+	case t.Kind() == reflect.Interface && reflect.TypeOf(RedactedValue).Implements(t):
+		c := reflect.New(t).Elem()
+		c.Set(reflect.ValueOf(RedactedValue))
+		return c
+	default:
+		return reflect.Zero(t)
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	default:
+		return false
+	}
+}
+
+// RedactHook is a logrus hook redacting the fields of log entries with Redact, and the fields whose name matches
+// RedactedKeys.
+type RedactHook struct{}
+
+func (RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (RedactHook) Fire(entry *logrus.Entry) error {
+	for k, v := range entry.Data {
+		if IsRedactedKey(k) {
+			entry.Data[k] = RedactedValue
+			continue
+		}
+		entry.Data[k] = Redact(v)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package util
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	type Credentials struct {
+		Username string
+		Hash     string  `redact:"true"`
+		PIN      *string `redact:"true"`
+		Attempts int     `redact:"true"`
+	}
+	type User struct {
+		Name        string
+		Credentials *Credentials
+		Extra       map[string]interface{}
+		Sessions    []Credentials
+	}
+
+	pin := "1234"
+	input := User{
+		Name: testString,
+		Credentials: &Credentials{
+			Username: testString,
+			Hash:     testString,
+			PIN:      &pin,
+			Attempts: testInt,
+		},
+		Extra: map[string]interface{}{
+			"Password":      testString,
+			"refresh_token": testInt,
+			"nested":        map[string]interface{}{"client_secret": testString, "name": testString},
+		},
+		Sessions: []Credentials{{Username: testString, Hash: testString}},
+	}
+
+	resp := Redact(input)
+
+	a := assert.New(t)
+	a.Equal(User{
+		Name: testString,
+		Credentials: &Credentials{
+			Username: testString,
+			Hash:     RedactedValue,
+		},
+		Extra: map[string]interface{}{
+			"Password":      RedactedValue,
+			"refresh_token": RedactedValue,
+			"nested":        map[string]interface{}{"client_secret": RedactedValue, "name": testString},
+		},
+		Sessions: []Credentials{{Username: testString, Hash: RedactedValue}},
+	}, resp)
+
+	// The input is left untouched.
+	a.Equal(testString, input.Credentials.Hash)
+	a.Equal(testString, input.Extra["Password"])
+	a.Equal(testString, input.Sessions[0].Hash)
+}
+
+func TestIsRedactedKey(t *testing.T) {
+	a := assert.New(t)
+	a.True(IsRedactedKey("password"))
+	a.True(IsRedactedKey("AccessToken"))
+	a.True(IsRedactedKey("client-secret"))
+	a.False(IsRedactedKey("token_type"))
+	a.False(IsRedactedKey("next_page_token"))
+	a.False(IsRedactedKey("password_set"))
+}
+
+func TestRedactKeys(t *testing.T) {
+	input := map[string]interface{}{"token": testString, "pin": testInt}
+
+	a := assert.New(t)
+	a.Equal(input, RedactKeys(input, nil))
+	a.Equal(map[string]interface{}{"token": testString, "pin": RedactedValue}, RedactKeys(input, []string{"PIN"}))
+}
+
+func TestRedact_Cyclic(t *testing.T) {
+	type node struct {
+		Name   string
+		Secret string `redact:"true"`
+		Parent *node
+		Extra  map[string]interface{}
+	}
+
+	root := &node{Name: testString, Secret: testString, Extra: map[string]interface{}{}}
+	root.Parent = root
+	root.Extra["self"] = root.Extra
+	root.Extra["node"] = root
+
+	resp := Redact(root)
+
+	a := assert.New(t)
+	a.Equal(RedactedValue, resp.Secret)
+	a.Same(resp, resp.Parent)
+	a.Same(resp, resp.Extra["node"])
+	a.Equal(testString, root.Secret)
+
+	// Log entries holding cyclic values go through the hook as well.
+	child := &node{Name: testString, Secret: testString}
+	child.Parent = &node{Name: testString, Parent: child}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(RedactHook{})
+	logger.WithField("node", *child).Info("cyclic")
+	a.Contains(buf.String(), "cyclic")
+	a.NotContains(buf.String(), "Secret:"+testString)
+}
+
+func TestRedact_Untyped(t *testing.T) {
+	var input interface{} = map[string]string{"token": testString, "name": testString}
+
+	resp := Redact(input)
+
+	assert.Equal(t, map[string]string{"token": RedactedValue, "name": testString}, resp)
+}
+
+func TestRedactHook(t *testing.T) {
+	type Login struct {
+		Username string
+		Password string `redact:"true"`
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(RedactHook{})
+
+	logger.WithFields(logrus.Fields{
+		"api_key": testString,
+		"login":   Login{Username: "user", Password: testString},
+	}).Info("login")
+
+	a := assert.New(t)
+	a.NotContains(buf.String(), testString)
+	a.Contains(buf.String(), "user")
+	a.Contains(buf.String(), RedactedValue)
+}
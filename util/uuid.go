@@ -0,0 +1,36 @@
+package util
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// NewUUIDv7 returns a new RFC 9562 version 7 UUID in its canonical string form.
+// Version 7 UUIDs start with a millisecond timestamp, so they sort by creation time.
+func NewUUIDv7() (string, error) {
+	u, err := generateRandomBytes(16)
+	if err != nil {
+		return "", err
+	}
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(u[:6], ts[2:])
+
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+
+	return string(buf), nil
+}
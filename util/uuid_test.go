@@ -0,0 +1,27 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	a := assert.New(t)
+
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, err := NewUUIDv7()
+	a.NoError(err)
+	a.Regexp(format, first)
+
+	time.Sleep(2 * time.Millisecond)
+
+	second, err := NewUUIDv7()
+	a.NoError(err)
+	a.Regexp(format, second)
+
+	a.NotEqual(first, second)
+	a.Less(first, second)
+}